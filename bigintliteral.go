@@ -0,0 +1,103 @@
+package modeledjs
+
+// otto's parser (github.com/robertkrimen/otto/parser) predates BigInt and
+// has no notion of a `123n` numeric literal — it lexes "123" followed by a
+// bare "n", which fails as a syntax error. preprocessBigIntLiterals runs
+// ahead of parser.ParseFile and rewrites every bare-decimal BigInt literal
+// into an equivalent `BigInt("123")` call, which otto already parses as an
+// ordinary CallExpression and which coerceToBigInt already evaluates to
+// the right JSBigInt. It only handles the plain decimal form (no 0x/0o/0b
+// prefix, no `_` digit separators); any other digit run followed by `n` is
+// left as it is, so otto still rejects it as a syntax error.
+//
+// This is a textual pass, not a true tokenizer: it tracks just enough
+// lexical state (single/double-quoted strings, template literals, line
+// and block comments) to avoid rewriting a digit run that only looks like
+// a BigInt literal because it's sitting inside one of those. It does not
+// track regex literals, so a numeral immediately followed by `n` inside a
+// /regex/ will be (mis)rewritten; this is a known gap, not a silent one.
+func preprocessBigIntLiterals(src []byte) []byte {
+	var out []byte
+	inSingle, inDouble, inTemplate, inLineComment, inBlockComment := false, false, false, false, false
+
+	isIdentPart := func(b byte) bool {
+		return b == '_' || b == '$' ||
+			(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+	}
+
+	for i := 0; i < len(src); i++ {
+		b := src[i]
+
+		switch {
+		case inLineComment:
+			out = append(out, b)
+			if b == '\n' {
+				inLineComment = false
+			}
+			continue
+
+		case inBlockComment:
+			out = append(out, b)
+			if b == '*' && i+1 < len(src) && src[i+1] == '/' {
+				out = append(out, '/')
+				i++
+				inBlockComment = false
+			}
+			continue
+
+		case inSingle || inDouble || inTemplate:
+			out = append(out, b)
+			if b == '\\' && i+1 < len(src) {
+				out = append(out, src[i+1])
+				i++
+				continue
+			}
+			switch {
+			case inSingle && b == '\'':
+				inSingle = false
+			case inDouble && b == '"':
+				inDouble = false
+			case inTemplate && b == '`':
+				inTemplate = false
+			}
+			continue
+		}
+
+		switch {
+		case b == '/' && i+1 < len(src) && src[i+1] == '/':
+			inLineComment = true
+			out = append(out, b)
+		case b == '/' && i+1 < len(src) && src[i+1] == '*':
+			inBlockComment = true
+			out = append(out, b)
+		case b == '\'':
+			inSingle = true
+			out = append(out, b)
+		case b == '"':
+			inDouble = true
+			out = append(out, b)
+		case b == '`':
+			inTemplate = true
+			out = append(out, b)
+
+		case b >= '0' && b <= '9' && (len(out) == 0 || !isIdentPart(out[len(out)-1])):
+			start := i
+			for i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9' {
+				i++
+			}
+			if i+1 < len(src) && src[i+1] == 'n' && (i+2 >= len(src) || !isIdentPart(src[i+2])) {
+				out = append(out, "BigInt(\""...)
+				out = append(out, src[start:i+1]...)
+				out = append(out, "\")"...)
+				i++ // consume the trailing 'n'
+			} else {
+				out = append(out, src[start:i+1]...)
+			}
+
+		default:
+			out = append(out, b)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,63 @@
+// Command coverreport reads a coverage profile written by cmd/run262's
+// -coverProfile flag and prints per-file and per-AST-kind hit ratios, to
+// help contributors see which language features the currently-passing
+// test262 subset actually exercises.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"com.github.sebastianobarrera.modeledjs/modeledjs"
+)
+
+var profilePath = flag.String("profile", "", "Path to a coverage profile written by run262 -coverProfile")
+
+func main() {
+	flag.Parse()
+
+	if *profilePath == "" {
+		log.Fatalf("command line argument is required: -profile (see -help)")
+	}
+
+	f, err := os.Open(*profilePath)
+	if err != nil {
+		log.Fatalf("opening profile: %s", err)
+	}
+	defer f.Close()
+
+	entries, err := modeledjs.ReadProfile(f)
+	if err != nil {
+		log.Fatalf("parsing profile: %s", err)
+	}
+
+	byFile, byKind, err := modeledjs.AnalyzeCoverage(entries)
+	if err != nil {
+		log.Fatalf("analyzing profile: %s", err)
+	}
+
+	fmt.Println("per-file hit ratios:")
+	printRatios(byFile)
+
+	fmt.Println()
+	fmt.Println("per-AST-kind hit ratios:")
+	printRatios(byKind)
+}
+
+// printRatios prints one "name\thit/total\tratio" line per entry in
+// stats, sorted by name for stable, diffable output.
+func printRatios(stats map[string]modeledjs.CoverageStats) {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		s := stats[name]
+		fmt.Printf("  %s\t%d/%d\t%.1f%%\n", name, s.Hit, s.Total, s.Ratio()*100)
+	}
+}
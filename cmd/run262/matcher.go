@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var runPattern = flag.String("run", "", "Only run test262 cases whose path/mode components match this slash-separated regexp, as in `go test -run`")
+var skipPattern = flag.String("skip", "", "Skip test262 cases whose path/mode components match this slash-separated regexp (same syntax as -run)")
+
+// matcher implements the `go test -run` style of filtering: the pattern is
+// split on '/', and each slash-separated regexp is matched against the
+// corresponding component of the case being considered. Here, a case's
+// components are its test262 path split on '/', plus a synthetic final
+// component of "strict" or "sloppy" for the execution mode. A pattern with
+// fewer components than the case matches a prefix; one with more never
+// matches.
+type matcher struct {
+	parts []*regexp.Regexp
+}
+
+// newMatcher compiles pattern, or returns a nil matcher (which matches
+// everything) for an empty pattern.
+func newMatcher(pattern string) (*matcher, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	rawParts := strings.Split(pattern, "/")
+	parts := make([]*regexp.Regexp, len(rawParts))
+	for i, raw := range rawParts {
+		re, err := regexp.Compile(raw)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = re
+	}
+	return &matcher{parts: parts}, nil
+}
+
+// Match reports whether the given test262 path and strict-mode flag satisfy
+// the pattern. A nil matcher matches everything.
+func (m *matcher) Match(testCasePath string, strictMode bool) bool {
+	if m == nil {
+		return true
+	}
+
+	mode := "sloppy"
+	if strictMode {
+		mode = "strict"
+	}
+
+	components := append(strings.Split(testCasePath, "/"), mode)
+	if len(m.parts) > len(components) {
+		return false
+	}
+
+	for i, re := range m.parts {
+		if !re.MatchString(components[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// caseFilter combines a -run matcher with a -skip matcher into the single
+// predicate runMany needs: a case runs only if -run selects it and -skip
+// doesn't exclude it.
+type caseFilter struct {
+	run  *matcher
+	skip *matcher
+}
+
+// newCaseFilter compiles both the -run and -skip patterns.
+func newCaseFilter(runPattern, skipPattern string) (*caseFilter, error) {
+	run, err := newMatcher(runPattern)
+	if err != nil {
+		return nil, fmt.Errorf("-run: %w", err)
+	}
+	skip, err := newMatcher(skipPattern)
+	if err != nil {
+		return nil, fmt.Errorf("-skip: %w", err)
+	}
+	return &caseFilter{run: run, skip: skip}, nil
+}
+
+// Match reports whether testCasePath/strictMode should run: it must match
+// the -run pattern (or -run must be empty) and must not match -skip.
+func (f *caseFilter) Match(testCasePath string, strictMode bool) bool {
+	if f == nil {
+		return true
+	}
+	if !f.run.Match(testCasePath, strictMode) {
+		return false
+	}
+	return f.skip == nil || !f.skip.Match(testCasePath, strictMode)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// reportSpec is one `-report=<format>:<path>` flag occurrence.
+type reportSpec struct {
+	format string
+	path   string
+}
+
+// reportSpecs collects every `-report` flag given on the command line, in
+// the order they were given. flag.Var lets the flag repeat.
+type reportSpecs []reportSpec
+
+func (rs *reportSpecs) String() string {
+	parts := make([]string, len(*rs))
+	for i, spec := range *rs {
+		parts[i] = spec.format + ":" + spec.path
+	}
+	return strings.Join(parts, ",")
+}
+
+func (rs *reportSpecs) Set(value string) error {
+	format, path, found := strings.Cut(value, ":")
+	if !found {
+		return fmt.Errorf("invalid -report value %q: expected <format>:<path>", value)
+	}
+	switch format {
+	case "json", "jsonl", "junit", "tap":
+	default:
+		return fmt.Errorf("invalid -report format %q: must be one of json, jsonl, junit, tap", format)
+	}
+	*rs = append(*rs, reportSpec{format: format, path: path})
+	return nil
+}
+
+var reports reportSpecs
+
+func init() {
+	flag.Var(&reports, "report", "Write a machine-readable report in the given format (json, jsonl, junit, tap) to the given path; may be repeated")
+}
+
+// writeReports emits every report requested via -report against result.
+func writeReports(result RunManyResult) error {
+	for _, spec := range reports {
+		f, err := os.Create(spec.path)
+		if err != nil {
+			return fmt.Errorf("creating report file %s: %w", spec.path, err)
+		}
+
+		switch spec.format {
+		case "json":
+			err = writeJSONReport(f, result)
+		case "jsonl":
+			err = writeJSONLReport(f, result)
+		case "junit":
+			err = writeJUnitReport(f, result)
+		case "tap":
+			err = writeTAPReport(f, result)
+		default:
+			panic("unreachable: invalid report format should have been rejected by reportSpecs.Set")
+		}
+
+		closeErr := f.Close()
+		if err != nil {
+			return fmt.Errorf("writing %s report to %s: %w", spec.format, spec.path, err)
+		}
+		if closeErr != nil {
+			return fmt.Errorf("closing report file %s: %w", spec.path, closeErr)
+		}
+	}
+	return nil
+}
+
+func writeJSONReport(w io.Writer, result RunManyResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// jsonlSummary is the final line writeJSONLReport appends after one object
+// per CaseOutcome, so a streaming consumer doesn't have to buffer the
+// whole file to get aggregate counts.
+type jsonlSummary struct {
+	Summary   bool `json:"summary"`
+	Total     int  `json:"total"`
+	Successes int  `json:"successes"`
+	Skipped   int  `json:"skipped"`
+	Failures  int  `json:"failures"`
+}
+
+// writeJSONLReport emits one JSON object per CaseOutcome, one per line,
+// followed by a jsonlSummary line — the same data writeJSONReport nests
+// under one top-level document, reshaped for a consumer that wants to
+// process results incrementally instead of parsing the whole report at
+// once.
+func writeJSONLReport(w io.Writer, result RunManyResult) error {
+	enc := json.NewEncoder(w)
+	summary := jsonlSummary{Summary: true, Total: len(result.Cases)}
+
+	for _, co := range result.Cases {
+		if err := enc.Encode(co); err != nil {
+			return err
+		}
+		switch {
+		case co.Skipped:
+			summary.Skipped++
+		case co.Success:
+			summary.Successes++
+		default:
+			summary.Failures++
+		}
+	}
+
+	return enc.Encode(summary)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name    string        `xml:"name,attr"`
+	Time    float64       `xml:"time,attr"`
+	Failure *junitFailure `xml:"failure,omitempty"`
+	Skipped *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// errorKind classifies co's failure, distinguishing the three ways a
+// test262 case fails that matter when triaging a report: the case never
+// got to run at all, a negative test's expected error didn't materialize
+// (or the wrong one did), or the failure happened during ordinary
+// execution. Shared by CaseOutcome's JSON encoding (the ErrorKind field)
+// and writeJUnitReport (the `type` attribute on <failure>).
+func errorKind(co CaseOutcome) string {
+	switch {
+	case co.TimedOut:
+		return "timeout"
+	case co.Panic != "":
+		return "panic"
+	case co.Error == nil:
+		return "failure"
+	}
+	// checkNegativeOutcome's errors all start with "expected"; everything
+	// else reaching here is either a parse error (readHarnessFile/
+	// ParseReader) or a runtime one from RunScriptReader.
+	msg := co.Error.Error()
+	switch {
+	case strings.HasPrefix(msg, "expected "):
+		return "negative-phase-mismatch"
+	case strings.Contains(msg, "parse"):
+		return "parse-error"
+	default:
+		return "runtime-error"
+	}
+}
+
+// writeJUnitReport emits one <testsuite> per test262 file (not per
+// top-level directory): test262 cases always run in both strict and
+// sloppy mode, so each file's suite holds at most two <testcase>s, one
+// per mode, keeping the file↔result mapping in the XML as direct as the
+// one test262 itself uses.
+func writeJUnitReport(w io.Writer, result RunManyResult) error {
+	suiteByPath := make(map[string]*junitTestSuite)
+	var order []string
+
+	for _, co := range result.Cases {
+		suite, ok := suiteByPath[co.Path]
+		if !ok {
+			suite = &junitTestSuite{Name: co.Path}
+			suiteByPath[co.Path] = suite
+			order = append(order, co.Path)
+		}
+
+		mode := "sloppy"
+		if co.StrictMode {
+			mode = "strict"
+		}
+
+		tc := junitTestCase{
+			Name: mode,
+			Time: co.Duration.Seconds(),
+		}
+		switch {
+		case co.Skipped:
+			tc.Skipped = &struct{}{}
+			suite.Skipped++
+		case !co.Success:
+			msg := ""
+			if co.Error != nil {
+				msg = co.Error.Error()
+			}
+			tc.Failure = &junitFailure{Message: firstLine(msg), Type: errorKind(co), Body: msg}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	doc := junitTestSuites{}
+	for _, p := range order {
+		doc.Suites = append(doc.Suites, *suiteByPath[p])
+	}
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// writeTAPReport writes the result following the TAP version 13
+// specification: https://testanything.org/tap-version-13-specification.html
+func writeTAPReport(w io.Writer, result RunManyResult) error {
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", len(result.Cases))
+
+	for i, co := range result.Cases {
+		mode := "sloppy"
+		if co.StrictMode {
+			mode = "strict"
+		}
+		desc := fmt.Sprintf("%s (%s)", co.Path, mode)
+
+		switch {
+		case co.Skipped:
+			fmt.Fprintf(w, "ok %d - %s # SKIP\n", i+1, desc)
+		case co.Success:
+			fmt.Fprintf(w, "ok %d - %s\n", i+1, desc)
+		default:
+			fmt.Fprintf(w, "not ok %d - %s\n", i+1, desc)
+			fmt.Fprintln(w, "  ---")
+			if co.Error != nil {
+				fmt.Fprintf(w, "  message: %q\n", firstLine(co.Error.Error()))
+			}
+			fmt.Fprintf(w, "  timedOut: %v\n", co.TimedOut)
+			if co.Panic != "" {
+				fmt.Fprintf(w, "  panic: %q\n", co.Panic)
+			}
+			fmt.Fprintln(w, "  ...")
+		}
+	}
+
+	return nil
+}
@@ -2,15 +2,19 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"runtime/pprof"
 
@@ -22,21 +26,52 @@ import (
 )
 
 var (
-	test262Root = flag.String("test262", "", "Path to the test262 respository")
-	testCase    = flag.String("single", "", "Run this specific testcase (path relative to the test262 root)")
-	showAST     = flag.Bool("showAST", false, "Show the AST of the main script")
-	parseOnly   = flag.Bool("parseOnly", false, "Stop at parsing; test is successful if it parses as expected")
-	cpuProfile  = flag.String("cpuProfile", "", "Write CPU profile to this file")
-
-	textSta    string
-	textAssert string
+	test262Root  = flag.String("test262", "", "Path to the test262 respository")
+	testCase     = flag.String("single", "", "Run this specific testcase (path relative to the test262 root)")
+	showAST      = flag.Bool("showAST", false, "Show the AST of the main script")
+	parseOnly    = flag.Bool("parseOnly", false, "Stop at parsing; test is successful if it parses as expected")
+	cpuProfile   = flag.String("cpuProfile", "", "Write CPU profile to this file")
+	featuresFlag = flag.String("features", "", "Comma-separated list of test262 `features` this VM implements; cases requiring any other feature are skipped")
+	jobs         = flag.Int("jobs", runtime.NumCPU(), "Number of test262 cases to run in parallel")
+	caseTimeout  = flag.Duration("timeout", 10*time.Second, "Fail a case that takes longer than this (the timed-out case is not stopped; it keeps running in the background)")
+	interpFlag   = flag.String("interp", "bytecode", "Evaluator Invoke uses for function bodies: \"tree\" or \"bytecode\"")
+	listFlag     = flag.Bool("list", false, "Print the filtered set of test262 cases (path and mode), one per line, and exit without running them")
+	coverProfile = flag.String("coverProfile", "", "Enable coverage on every VM and write the merged profile to this file; read it with cmd/coverreport")
+	watchFlag    = flag.Bool("watch", false, "Stay running: re-run the affected test262 subset whenever test262 files change, and exit with a distinct code when Go sources change so an outer `go run` wrapper can rebuild")
+	watchDelay   = flag.Duration("watchDelay", 250*time.Millisecond, "Debounce window for -watch: wait this long after the last change before re-running")
 
 	ErrCaseDisabledInMetadata = errors.New("testcase disabled in metadata")
+	ErrCaseSkippedFeature     = errors.New("testcase skipped: requires unimplemented feature")
+	ErrCaseSkippedFilter      = errors.New("testcase skipped: excluded by -run/-skip filter")
 )
 
+// supportedFeatures is populated from -features at startup; a nil map means no filtering is applied.
+var supportedFeatures map[string]bool
+
+// coverage is non-nil when -coverProfile is set, shared by every VM
+// runTestCaseMode creates (across every worker goroutine runMany
+// spawns) so execution hits from the whole batch land in one profile.
+var coverage *modeledjs.Coverage
+
+func parseFeaturesFlag(s string) map[string]bool {
+	if s == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, feat := range strings.Split(s, ",") {
+		feat = strings.TrimSpace(feat)
+		if feat != "" {
+			set[feat] = true
+		}
+	}
+	return set
+}
+
 func main() {
 	flag.Parse()
 
+	supportedFeatures = parseFeaturesFlag(*featuresFlag)
+
 	if *cpuProfile != "" {
 		cpuf, err := os.Create(*cpuProfile)
 		if err != nil {
@@ -46,22 +81,32 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	if *coverProfile != "" {
+		coverage = modeledjs.NewCoverage()
+		defer func() {
+			covf, err := os.Create(*coverProfile)
+			if err != nil {
+				log.Fatalf("can't create coverage profile file: %s: %s", *coverProfile, err)
+			}
+			defer covf.Close()
+			if err := coverage.WriteProfile(covf); err != nil {
+				log.Fatalf("writing coverage profile: %s", err)
+			}
+		}()
+	}
+
 	if *test262Root == "" {
 		log.Fatalf("command line argument is required: -test262 (see -help)")
 		os.Exit(1)
 	}
 
-	var raw []byte
-	raw, err := os.ReadFile(path.Join(*test262Root, "harness/sta.js"))
-	if err != nil {
-		log.Fatalf("while reading preamble (harness/sta.js): %s", err)
-	}
-	textSta = string(raw)
-	raw, err = os.ReadFile(path.Join(*test262Root, "harness/assert.js"))
-	if err != nil {
-		log.Fatalf("while reading preamble (harness/assert.js): %s", err)
+	// warm the harness cache now so a typo in -test262 fails fast, instead
+	// of surfacing as a per-case failure once the batch is already running.
+	for _, harnessFile := range []string{"harness/sta.js", "harness/assert.js"} {
+		if _, err := readHarnessFile(path.Join(*test262Root, harnessFile)); err != nil {
+			log.Fatalf("while reading preamble (%s): %s", harnessFile, err)
+		}
 	}
-	textAssert = string(raw)
 
 	if *testCase != "" {
 		log.Println("running single test case:", *testCase)
@@ -74,62 +119,105 @@ func main() {
 			log.Fatalf("while parsing testConfig.json: %s", err)
 		}
 
-		result := runMany(*test262Root, testConfig.TestCases)
+		filter, err := newCaseFilter(*runPattern, *skipPattern)
+		if err != nil {
+			log.Fatalf("invalid filter pattern: %s", err)
+		}
 
-		successesCount := 0
-		failuresCount := 0
-		for _, co := range result.Cases {
-			if co.Success {
-				successesCount++
-			} else {
-				failuresCount++
+		if *listFlag {
+			for _, relPath := range testConfig.TestCases {
+				for _, strictMode := range []bool{true, false} {
+					if !filter.Match(relPath, strictMode) {
+						continue
+					}
+					mode := "sloppy"
+					if strictMode {
+						mode = "strict"
+					}
+					fmt.Printf("%s/%s\n", relPath, mode)
+				}
 			}
+			return
 		}
 
-		successes := make([]CaseOutcome, 0, successesCount)
-		failures := make([]CaseOutcome, 0, failuresCount)
-		for _, co := range result.Cases {
-			if co.Success {
-				successes = append(successes, co)
-			} else {
-				failures = append(failures, co)
-			}
+		if *watchFlag {
+			runWatch(*test262Root, testConfig, filter)
+			return
 		}
 
-		fmt.Printf("group SUCCESSES %d\n", successesCount)
-		for _, co := range successes {
-			strictMode := "sloppy"
-			if co.StrictMode {
-				strictMode = "strict"
-			}
-			fmt.Printf("case\t%s\t%s\n", co.Path, strictMode)
+		result := runMany(*test262Root, testConfig.TestCases, filter)
+		if err := reportResult(result); err != nil {
+			log.Fatalf("writing reports: %s", err)
 		}
+	}
+}
 
-		fmt.Printf("group FAILURES %d\n", failuresCount)
-		for _, co := range failures {
-			strictMode := "sloppy"
-			if co.StrictMode {
-				strictMode = "strict"
-			}
+// reportResult writes every -report requested against result, then prints
+// the same case-by-case/summary breakdown to stdout that the one-shot path
+// has always produced. runWatch calls this too, once per re-run, so a
+// developer iterating under -watch sees the identical output they'd get
+// from a one-shot invocation of the affected subset.
+func reportResult(result RunManyResult) error {
+	if err := writeReports(result); err != nil {
+		return err
+	}
 
-			fmt.Printf("case\t%s\t%s\n", co.Path, strictMode)
+	var successes, failures, skipped []CaseOutcome
+	for _, co := range result.Cases {
+		switch {
+		case co.Skipped:
+			skipped = append(skipped, co)
+		case co.Success:
+			successes = append(successes, co)
+		default:
+			failures = append(failures, co)
+		}
+	}
 
-			var errLines []string
-			if co.Error != nil {
-				errLines = strings.Split(co.Error.Error(), "\n")
-			}
-			for ndx, line := range errLines {
-				if ndx == 0 {
-					fmt.Printf("error\t\t%s\n", line)
-				} else {
-					fmt.Printf("ectx\t\t%s\n", line)
-				}
-			}
+	fmt.Printf("group SUCCESSES %d\n", len(successes))
+	for _, co := range successes {
+		strictMode := "sloppy"
+		if co.StrictMode {
+			strictMode = "strict"
 		}
+		fmt.Printf("case\t%s\t%s\n", co.Path, strictMode)
+	}
 
-		fmt.Printf("summary\ttotal: %d; %d successes; %d failures\n", len(result.Cases), successesCount, failuresCount)
+	fmt.Printf("group SKIPPED %d\n", len(skipped))
+	for _, co := range skipped {
+		strictMode := "sloppy"
+		if co.StrictMode {
+			strictMode = "strict"
+		}
+		fmt.Printf("case\t%s\t%s\n", co.Path, strictMode)
+	}
 
+	fmt.Printf("group FAILURES %d\n", len(failures))
+	for _, co := range failures {
+		strictMode := "sloppy"
+		if co.StrictMode {
+			strictMode = "strict"
+		}
+
+		fmt.Printf("case\t%s\t%s\n", co.Path, strictMode)
+
+		var errLines []string
+		if co.Error != nil {
+			errLines = strings.Split(co.Error.Error(), "\n")
+		}
+		for ndx, line := range errLines {
+			if ndx == 0 {
+				fmt.Printf("error\t\t%s\n", line)
+			} else {
+				fmt.Printf("ectx\t\t%s\n", line)
+			}
+		}
 	}
+
+	fmt.Printf("summary\ttotal: %d; %d successes; %d skipped; %d failures\n",
+		len(result.Cases), len(successes), len(skipped), len(failures))
+
+	return nil
 }
 
 type TestConfig struct {
@@ -154,42 +242,186 @@ type CaseOutcome struct {
 	Path       string
 	StrictMode bool
 
-	Success bool
-	Error   error
+	Success  bool
+	Skipped  bool
+	Error    error
+	Duration time.Duration
+	TimedOut bool
+	Panic    string
 }
 
-func runMany(test262Root string, testCases []string) (result RunManyResult) {
-	result.Cases = make([]CaseOutcome, 0, len(testCases)*2)
+// caseOutcomeJSON mirrors CaseOutcome but with Error flattened to a string,
+// since error doesn't implement json.Marshaler.
+type caseOutcomeJSON struct {
+	Path       string
+	StrictMode bool
+	Success    bool
+	Skipped    bool
+	Error      string `json:",omitempty"`
+	ErrorKind  string `json:",omitempty"`
+	Duration   time.Duration
+	TimedOut   bool
+	Panic      string `json:",omitempty"`
+}
 
-	sink := make(chan CaseOutcome)
+func (co CaseOutcome) MarshalJSON() ([]byte, error) {
+	out := caseOutcomeJSON{
+		Path:       co.Path,
+		StrictMode: co.StrictMode,
+		Success:    co.Success,
+		Skipped:    co.Skipped,
+		Duration:   co.Duration,
+		TimedOut:   co.TimedOut,
+		Panic:      co.Panic,
+	}
+	if co.Error != nil {
+		out.Error = co.Error.Error()
+		out.ErrorKind = errorKind(co)
+	}
+	return json.Marshal(out)
+}
+
+// caseWork is one (path, mode) unit dispatched to the worker pool, tagged
+// with its position in the deterministic (path, mode) enumeration order so
+// result.Cases comes out in that order regardless of which worker finishes
+// first.
+type caseWork struct {
+	index      int
+	path       string
+	strictMode bool
+}
 
+// runMany runs every (path, strictMode) pair produced by testCases across a
+// pool of -jobs worker goroutines, each owning its own modeledjs.VM for the
+// case it's currently running. A case that panics or exceeds -timeout is
+// recorded as a failed CaseOutcome rather than taking down the batch. Pairs
+// excluded by filter are still reported, as skipped, rather than omitted.
+// result.Cases is always ordered by testCases's own order (each path's
+// strict variant immediately followed by its sloppy one), not by
+// completion order, so two runs of the same filtered set produce an
+// identically-ordered report.
+func runMany(test262Root string, testCases []string, filter *caseFilter) (result RunManyResult) {
+	total := len(testCases) * 2
+	work := make(chan caseWork, total)
+	result.Cases = make([]CaseOutcome, total)
+
+	index := 0
 	for _, relPath := range testCases {
-		go func() {
-			errStrict, errSloppy := runTestCase(test262Root, relPath)
+		for _, strictMode := range []bool{true, false} {
+			if filter.Match(relPath, strictMode) {
+				work <- caseWork{index: index, path: relPath, strictMode: strictMode}
+			} else {
+				result.Cases[index] = CaseOutcome{
+					Path:       relPath,
+					StrictMode: strictMode,
+					Skipped:    true,
+					Error:      ErrCaseSkippedFilter,
+				}
+			}
+			index++
+		}
+	}
+	close(work)
+
+	numWorkers := *jobs
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
 
-			sink <- CaseOutcome{
-				Path:       relPath,
-				StrictMode: true,
-				Success:    (errStrict == nil || errStrict == ErrCaseDisabledInMetadata),
-				Error:      errStrict,
+	var done, failing int64
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	for w := 0; w < numWorkers; w++ {
+		go func() {
+			defer wg.Done()
+			for item := range work {
+				co := runCaseIsolated(test262Root, item.path, item.strictMode)
+				result.Cases[item.index] = co
+
+				n := atomic.AddInt64(&done, 1)
+				if !co.Success && !co.Skipped {
+					atomic.AddInt64(&failing, 1)
+				}
+				fmt.Fprintf(os.Stderr, "%d/%d done, %d failing\n", n, total, atomic.LoadInt64(&failing))
 			}
-			sink <- CaseOutcome{
-				Path:       relPath,
-				StrictMode: false,
-				Success:    (errSloppy == nil || errSloppy == ErrCaseDisabledInMetadata),
-				Error:      errSloppy,
+		}()
+	}
+	wg.Wait()
+
+	return
+}
+
+// runCaseIsolated runs a single (path, strictMode) case with a -timeout
+// deadline and panic recovery, so that a hang or a crash inside the VM
+// surfaces as a CaseOutcome instead of killing the worker.
+func runCaseIsolated(test262Root, path string, strictMode bool) CaseOutcome {
+	ctx, cancel := context.WithTimeout(context.Background(), *caseTimeout)
+	defer cancel()
+
+	type result struct {
+		err   error
+		panic string
+	}
+	done := make(chan result, 1)
+
+	start := time.Now()
+	go func() {
+		var res result
+		defer func() {
+			if r := recover(); r != nil {
+				res.panic = fmt.Sprint(r)
 			}
+			done <- res
 		}()
+		res.err = runTestCaseMode(test262Root, path, strictMode)
+	}()
+
+	select {
+	case res := <-done:
+		co := caseOutcome(path, strictMode, res.err)
+		co.Duration = time.Since(start)
+		if res.panic != "" {
+			co.Success = false
+			co.Panic = res.panic
+			co.Error = fmt.Errorf("panic: %s", res.panic)
+		}
+		return co
+	case <-ctx.Done():
+		return CaseOutcome{
+			Path:       path,
+			StrictMode: strictMode,
+			Duration:   time.Since(start),
+			TimedOut:   true,
+			Error:      fmt.Errorf("case exceeded timeout of %s", *caseTimeout),
+		}
 	}
+}
 
-	for i := 0; i < len(testCases); i++ {
-		co := <-sink
-		result.Cases = append(result.Cases, co)
+func caseOutcome(path string, strictMode bool, err error) CaseOutcome {
+	return CaseOutcome{
+		Path:       path,
+		StrictMode: strictMode,
+		Success:    err == nil || err == ErrCaseDisabledInMetadata,
+		Skipped:    err == ErrCaseSkippedFeature,
+		Error:      err,
 	}
-	return
 }
 
+// runTestCase runs both the strict and sloppy variants of testCase, each
+// with its own VM, and is used by the -single CLI path. The batch path
+// (runMany) instead calls runTestCaseMode directly, one mode at a time, so
+// that each (path, mode) pair can be scheduled and timed out independently.
 func runTestCase(test262Root, testCase string) (errStrict, errSloppy error) {
+	errStrict = runTestCaseMode(test262Root, testCase, true)
+	errSloppy = runTestCaseMode(test262Root, testCase, false)
+	return
+}
+
+// runTestCaseMode runs a single (path, strictMode) variant of a test262
+// case to completion. It never calls log.Fatalf: every failure, including
+// an unreadable file, is returned as an error so that one bad case can't
+// take down a batch run.
+func runTestCaseMode(test262Root, testCase string, forceStrict bool) (err error) {
 	testCaseAbs := testCase
 	if !path.IsAbs(testCase) {
 		testCaseAbs = path.Join(test262Root, testCase)
@@ -197,101 +429,194 @@ func runTestCase(test262Root, testCase string) (errStrict, errSloppy error) {
 
 	textBytes, err := os.ReadFile(testCaseAbs)
 	if err != nil {
-		log.Fatalf("reading testcase %s: %v", testCaseAbs, err)
+		return fmt.Errorf("reading testcase %s: %w", testCaseAbs, err)
 	}
 
 	if *showAST {
-		err := modeledjs.PrintAST(bytes.NewReader(textBytes))
-
-		if err != nil {
-			log.Fatalf("parsing and printing AST: %v", err)
+		if err := modeledjs.PrintAST(bytes.NewReader(textBytes)); err != nil {
+			return fmt.Errorf("parsing and printing AST: %w", err)
 		}
 	}
 
 	mt, err := parseMetadata(textBytes)
 	if err != nil {
-		errStrict = fmt.Errorf("while parsing metadata: %w", err)
-		errSloppy = errStrict
-		return
+		return fmt.Errorf("while parsing metadata: %w", err)
 	}
 
-	runInMode := func(forceStrict bool) (err error) {
-		log.Printf("running %s (strict: %v)", testCase, forceStrict)
+	if missing := mt.missingFeature(); missing != "" {
+		log.Printf("skipping %s: requires feature %q", testCase, missing)
+		return ErrCaseSkippedFeature
+	}
 
-		vm := modeledjs.NewVM()
+	if forceStrict && mt.NoStrict {
+		return ErrCaseDisabledInMetadata
+	}
+	if !forceStrict && mt.OnlyStrict {
+		return ErrCaseDisabledInMetadata
+	}
 
-		paths := []string{
-			path.Join(test262Root, "harness/sta.js"),
-			path.Join(test262Root, "harness/assert.js"),
-		}
-		paths = append(paths, mt.Includes...)
-		paths = append(paths, testCaseAbs)
+	log.Printf("running %s (strict: %v)", testCase, forceStrict)
 
-		for i, path := range paths {
-			var buf *bytes.Buffer
+	vm := modeledjs.NewVM()
+	if err := vm.SetInterpreter(*interpFlag); err != nil {
+		return err
+	}
+	if coverage != nil {
+		vm.EnableCoverage(coverage)
+	}
 
-			if i == len(paths)-1 {
-				buf = bytes.NewBufferString("\"use strict\";")
-				io.Copy(buf, bytes.NewReader(textBytes))
-			} else {
-				buf = new(bytes.Buffer)
+	var doneCh chan error
+	if mt.Async {
+		doneCh = make(chan error, 1)
+	}
+	vm.InstallHost262(modeledjs.Host262Options{OnAsyncDone: doneCh})
 
-				f, err := os.Open(path)
-				if err != nil {
-					return err
-				}
-				defer f.Close()
+	paths := []string{
+		path.Join(test262Root, "harness/sta.js"),
+		path.Join(test262Root, "harness/assert.js"),
+	}
+	if mt.Async {
+		paths = append(paths, path.Join(test262Root, "harness/doneprintHandle.js"))
+	}
+	for _, inc := range mt.Includes {
+		paths = append(paths, path.Join(test262Root, "harness", inc))
+	}
+	paths = append(paths, testCaseAbs)
 
-				_, err = io.Copy(buf, f)
-				if err != nil {
-					return err
-				}
+	for i, p := range paths {
+		isTestCase := i == len(paths)-1
+
+		var src []byte
+		if isTestCase {
+			src = textBytes
+			if forceStrict {
+				src = append([]byte(`"use strict";`), textBytes...)
+			}
+		} else {
+			src, err = readHarnessFile(p)
+			if err != nil {
+				return err
 			}
+		}
 
+		if !isTestCase || mt.NegativePhase != "parse" {
 			if *parseOnly {
-				err = tsparser.ParseBytes(path, buf.Bytes())
+				err = tsparser.ParseBytes(p, src)
 			} else {
-				err = vm.RunScriptReader(path, buf)
+				err = vm.RunScriptReader(p, bytes.NewReader(src))
 			}
+		} else {
+			// negative parse-phase test: parsing itself must fail, and must
+			// not reach execution at all.
+			_, err = modeledjs.ParseReader(p, bytes.NewReader(src))
+		}
 
-			if mt.NegativePhase != "" {
-				if err == nil {
-					err = fmt.Errorf("expected %s error in phase %s, but none were raised", mt.NegativeType, mt.NegativePhase)
-				} else {
-					err = nil
-				}
-			}
+		if isTestCase && mt.NegativePhase != "" {
+			err = checkNegativeOutcome(mt, err)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
 
+	if mt.Async && err == nil {
+		select {
+		case err = <-doneCh:
 			if err != nil {
-				return err
+				return fmt.Errorf("async test failed: %w", err)
 			}
+		case <-time.After(*caseTimeout):
+			return fmt.Errorf("timed out waiting for $DONE() to be called")
 		}
+	}
 
-		return nil
+	return nil
+}
+
+// harnessCache holds the contents of harness/include files (sta.js,
+// assert.js, and whatever an individual case pulls in via `includes`),
+// keyed by absolute path, so a batch run reads each one from disk once
+// instead of once per (case, mode) pair.
+var (
+	harnessCacheMu sync.Mutex
+	harnessCache   = make(map[string][]byte)
+)
+
+func readHarnessFile(absPath string) ([]byte, error) {
+	harnessCacheMu.Lock()
+	defer harnessCacheMu.Unlock()
+
+	if src, ok := harnessCache[absPath]; ok {
+		return src, nil
 	}
 
-	if mt.NoStrict {
-		errStrict = ErrCaseDisabledInMetadata
-	} else {
-		errStrict = runInMode(true)
+	src, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
 	}
-	if mt.OnlyStrict {
-		errSloppy = ErrCaseDisabledInMetadata
-	} else {
-		errSloppy = runInMode(false)
+	harnessCache[absPath] = src
+	return src, nil
+}
+
+// checkNegativeOutcome inverts the outcome of running a negative test: the
+// case passes (returns nil) iff err is an error of the declared negative
+// type. When the runtime error doesn't carry enough information to compare
+// against NegativeType (e.g. a Go-level error rather than a thrown JS
+// exception), we fall back to accepting any non-nil error.
+func checkNegativeOutcome(mt Metadata, err error) error {
+	if err == nil {
+		return fmt.Errorf("expected %s error in phase %s, but none were raised", mt.NegativeType, mt.NegativePhase)
 	}
 
-	return
+	pexc, isJSExc := err.(modeledjs.ProgramException)
+	if !isJSExc {
+		// not a JS exception (e.g. a Go-level parse error): we can't check
+		// the error's constructor name, so any error is accepted.
+		return nil
+	}
+
+	gotClass := pexc.ErrorClassName()
+	if gotClass != "" && gotClass != mt.NegativeType {
+		return fmt.Errorf("expected %s error in phase %s, got %s: %w", mt.NegativeType, mt.NegativePhase, gotClass, err)
+	}
+	return nil
 }
 
+// Metadata holds the parsed test262 frontmatter (the YAML block delimited by
+// /*--- ---*/ at the top of every test262 case), covering the fields that
+// influence how runTestCase drives a case.
 type Metadata struct {
 	OnlyStrict    bool
 	NoStrict      bool
+	Async         bool
 	Includes      []string
+	Features      []string
+	Locale        []string
+	Es5id         string
+	Es6id         string
+	Esid          string
+	Description   string
+	Info          string
 	NegativePhase string
 	NegativeType  string
 }
 
+// missingFeature returns the first feature required by the case that isn't
+// listed in -features, or "" if the case can run (or -features was never
+// given, disabling the filter).
+func (mt Metadata) missingFeature() string {
+	if supportedFeatures == nil {
+		return ""
+	}
+	for _, feat := range mt.Features {
+		if !supportedFeatures[feat] {
+			return feat
+		}
+	}
+	return ""
+}
+
 func parseMetadata(text []byte) (mt Metadata, err error) {
 	startNdx := bytes.Index(text, []byte("/*---"))
 	if startNdx == -1 {
@@ -307,11 +632,20 @@ func parseMetadata(text []byte) (mt Metadata, err error) {
 	metadataYaml := text[startNdx+5 : endNdx]
 
 	var metadataRaw struct {
-		Flags    []string
-		Includes []string
-		Negative *struct {
+		Flags       []string
+		Includes    []string
+		Features    []string
+		Locale      []string
+		Es5id       string `yaml:"es5id"`
+		Es6id       string `yaml:"es6id"`
+		Esid        string `yaml:"esid"`
+		Description string
+		Info        string
+		Negative    *struct {
+			// Phase is one of "parse", "resolution", "runtime".
 			Phase string
-			Type  string
+			// Type is the expected error constructor name, e.g. "TypeError".
+			Type string
 		}
 	}
 
@@ -326,10 +660,19 @@ func parseMetadata(text []byte) (mt Metadata, err error) {
 			mt.NoStrict = true
 		case "onlyStrict":
 			mt.OnlyStrict = true
+		case "async":
+			mt.Async = true
 		}
 	}
 
 	mt.Includes = metadataRaw.Includes
+	mt.Features = metadataRaw.Features
+	mt.Locale = metadataRaw.Locale
+	mt.Es5id = metadataRaw.Es5id
+	mt.Es6id = metadataRaw.Es6id
+	mt.Esid = metadataRaw.Esid
+	mt.Description = metadataRaw.Description
+	mt.Info = metadataRaw.Info
 	if metadataRaw.Negative != nil {
 		mt.NegativePhase = metadataRaw.Negative.Phase
 		mt.NegativeType = metadataRaw.Negative.Type
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchExitRebuild is the process exit code runWatch uses when a Go source
+// file changes, distinguishing "please rebuild me" from every other exit
+// path (0 for a clean exit, log.Fatalf's 1 for a startup error) so an outer
+// `go run` wrapper loop knows to recompile and restart rather than treat
+// the exit as final.
+const watchExitRebuild = 42
+
+// goSourceRoot is the repository root fsnotify watches for Go source
+// changes, relative to the working directory the same way testConfig.json
+// and -test262 are: run262 is always invoked from the repo root.
+const goSourceRoot = "."
+
+// runWatch runs the filtered set once, then keeps running: a change under
+// goSourceRoot exits the process with watchExitRebuild, and a change under
+// test262Root re-runs only the cases it could have affected (the changed
+// case itself, or any case whose `includes` pulled in a changed harness
+// file), diffing the new outcomes against the last run so a regression
+// introduced mid-session is obvious without re-reading the whole report.
+func runWatch(test262Root string, testConfig TestConfig, filter *caseFilter) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Fatalf("-watch: creating filesystem watcher: %s", err)
+	}
+	defer watcher.Close()
+
+	if err := addRecursive(watcher, goSourceRoot); err != nil {
+		log.Fatalf("-watch: watching %s: %s", goSourceRoot, err)
+	}
+	if err := addRecursive(watcher, test262Root); err != nil {
+		log.Fatalf("-watch: watching %s: %s", test262Root, err)
+	}
+
+	includedBy := buildIncludeIndex(test262Root, testConfig.TestCases)
+
+	log.Printf("-watch: running initial batch of %d case(s)", len(testConfig.TestCases))
+	last := runMany(test262Root, testConfig.TestCases, filter)
+	if err := reportResult(last); err != nil {
+		log.Fatalf("writing reports: %s", err)
+	}
+
+	pending := make(map[string]bool)
+	var debounce *time.Timer
+	defer func() {
+		if debounce != nil {
+			debounce.Stop()
+		}
+	}()
+
+	for {
+		var debounceC <-chan time.Time
+		if debounce != nil {
+			debounceC = debounce.C
+		}
+
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Op.Has(fsnotify.Write) && !event.Op.Has(fsnotify.Create) && !event.Op.Has(fsnotify.Remove) && !event.Op.Has(fsnotify.Rename) {
+				continue
+			}
+
+			if isGoSource(event.Name) {
+				log.Printf("-watch: %s changed, exiting to rebuild", event.Name)
+				os.Exit(watchExitRebuild)
+			}
+
+			pending[event.Name] = true
+			if debounce == nil {
+				debounce = time.NewTimer(*watchDelay)
+			} else {
+				debounce.Reset(*watchDelay)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("-watch: filesystem watcher error: %s", err)
+
+		case <-debounceC:
+			changed := pending
+			pending = make(map[string]bool)
+			debounce = nil
+
+			affected := affectedCases(test262Root, changed, includedBy)
+			if len(affected) == 0 {
+				continue
+			}
+
+			log.Printf("-watch: re-running %d affected case(s)", len(affected))
+			result := runMany(test262Root, affected, filter)
+			printDiff(last, result)
+			last = mergeResult(last, result)
+			if err := reportResult(last); err != nil {
+				log.Printf("-watch: writing reports: %s", err)
+			}
+		}
+	}
+}
+
+// isGoSource reports whether absPath is a Go source file under
+// goSourceRoot, outside of submodules/ (vendored third-party code whose
+// changes don't call for rebuilding this module).
+func isGoSource(absPath string) bool {
+	if filepath.Ext(absPath) != ".go" {
+		return false
+	}
+	rel, err := filepath.Rel(goSourceRoot, absPath)
+	if err != nil {
+		return false
+	}
+	return !strings.HasPrefix(rel, "submodules"+string(filepath.Separator))
+}
+
+// addRecursive adds root and every directory beneath it to watcher,
+// skipping dot-directories (.git, in particular) since fsnotify only
+// watches the directories it's told about, not whole subtrees.
+func addRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if info.Name() != "." && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return watcher.Add(p)
+	})
+}
+
+// buildIncludeIndex maps a harness include's basename (as it appears in a
+// case's `includes` frontmatter, e.g. "compareArray.js") to every test262
+// case that includes it, so a change to that harness file can be turned
+// back into the set of cases it affects.
+func buildIncludeIndex(test262Root string, testCases []string) map[string][]string {
+	index := make(map[string][]string)
+	for _, relPath := range testCases {
+		text, err := os.ReadFile(path.Join(test262Root, relPath))
+		if err != nil {
+			continue
+		}
+		mt, err := parseMetadata(text)
+		if err != nil {
+			continue
+		}
+		for _, inc := range mt.Includes {
+			index[inc] = append(index[inc], relPath)
+		}
+	}
+	return index
+}
+
+// affectedCases turns the set of changed absolute paths under test262Root
+// into the list of test262 case paths (relative to test262Root) that need
+// re-running: a changed case file affects only itself, while a changed
+// harness file affects every case whose `includes` named it.
+func affectedCases(test262Root string, changed map[string]bool, includedBy map[string][]string) []string {
+	seen := make(map[string]bool)
+	var affected []string
+	add := func(relPath string) {
+		if !seen[relPath] {
+			seen[relPath] = true
+			affected = append(affected, relPath)
+		}
+	}
+
+	for absPath := range changed {
+		rel, err := filepath.Rel(test262Root, absPath)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasPrefix(rel, "harness/") {
+			for _, dependent := range includedBy[path.Base(rel)] {
+				add(dependent)
+			}
+		} else {
+			add(rel)
+		}
+	}
+
+	sort.Strings(affected)
+	return affected
+}
+
+// mergeResult folds a re-run of a subset of cases into the last full
+// result, so repeated re-runs under -watch keep last's coverage of the
+// whole filtered set instead of shrinking to whatever was most recently
+// affected.
+func mergeResult(last, update RunManyResult) RunManyResult {
+	byKey := make(map[caseKey]CaseOutcome, len(last.Cases))
+	var order []caseKey
+	for _, co := range last.Cases {
+		k := caseKey{co.Path, co.StrictMode}
+		if _, ok := byKey[k]; !ok {
+			order = append(order, k)
+		}
+		byKey[k] = co
+	}
+	for _, co := range update.Cases {
+		k := caseKey{co.Path, co.StrictMode}
+		if _, ok := byKey[k]; !ok {
+			order = append(order, k)
+		}
+		byKey[k] = co
+	}
+
+	merged := RunManyResult{Cases: make([]CaseOutcome, len(order))}
+	for i, k := range order {
+		merged.Cases[i] = byKey[k]
+	}
+	return merged
+}
+
+// caseKey identifies a (path, strictMode) pair, the same granularity
+// runMany schedules and reports on.
+type caseKey struct {
+	path       string
+	strictMode bool
+}
+
+// printDiff reports, for the subset of cases in update, how their outcome
+// changed relative to last: newly failing cases are the regressions a
+// -watch session exists to surface, newly passing and still-failing round
+// out the picture during iterative fixing.
+func printDiff(last, update RunManyResult) {
+	previous := make(map[caseKey]CaseOutcome, len(last.Cases))
+	for _, co := range last.Cases {
+		previous[caseKey{co.Path, co.StrictMode}] = co
+	}
+
+	var newlyFailing, newlyPassing, stillFailing []CaseOutcome
+	for _, co := range update.Cases {
+		prev, ok := previous[caseKey{co.Path, co.StrictMode}]
+		passed := co.Success || co.Skipped
+		switch {
+		case !ok:
+			if !passed {
+				newlyFailing = append(newlyFailing, co)
+			}
+		case passed && !(prev.Success || prev.Skipped):
+			newlyPassing = append(newlyPassing, co)
+		case !passed && (prev.Success || prev.Skipped):
+			newlyFailing = append(newlyFailing, co)
+		case !passed:
+			stillFailing = append(stillFailing, co)
+		}
+	}
+
+	printDiffGroup("NEWLY FAILING", newlyFailing)
+	printDiffGroup("NEWLY PASSING", newlyPassing)
+	printDiffGroup("STILL FAILING", stillFailing)
+}
+
+func printDiffGroup(label string, cases []CaseOutcome) {
+	if len(cases) == 0 {
+		return
+	}
+	log.Printf("-watch: %s (%d)", label, len(cases))
+	for _, co := range cases {
+		mode := "sloppy"
+		if co.StrictMode {
+			mode = "strict"
+		}
+		log.Printf("-watch:   %s\t%s", co.Path, mode)
+	}
+}
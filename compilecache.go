@@ -0,0 +1,206 @@
+package modeledjs
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// defaultCompileCacheSize is used when VMOptions.CompileCacheSize is left
+// at its zero value.
+const defaultCompileCacheSize = 64
+
+// VMOptions configures a VM at construction time. The zero value is valid
+// and picks the same defaults NewVM uses.
+type VMOptions struct {
+	// CompileCacheSize bounds how many CompiledScripts the VM's
+	// CompileCache keeps before evicting the least-recently-used entry.
+	CompileCacheSize int
+
+	// ModuleBaseDir, if non-empty, installs an FSLoader rooted at this
+	// directory as the VM's ModuleLoader. Leave empty and call
+	// SetModuleLoader directly for any other loader.
+	ModuleBaseDir string
+
+	// EnableConstantFolding turns on the optimizer pass (see optimizer.go)
+	// over every program this VM parses: literal binary/unary operations,
+	// `typeof` of a literal, and dead if/while branches are resolved at
+	// parse time instead of at every run. Off by default so tests can
+	// exercise the un-optimized interpreter path.
+	EnableConstantFolding bool
+
+	// MaxCallDepth bounds how many nested JSObject.Invoke calls (JS
+	// function calls, but also getters/setters and every native→JS
+	// callback, since none of those go through a flattened call stack —
+	// see VM.callDepth, which Invoke maintains) the VM allows before throwing a
+	// RangeError instead of recursing further into Go's own call stack.
+	// Left at its zero value, NewVMWithOptions picks defaultMaxCallDepth.
+	MaxCallDepth int
+}
+
+// defaultMaxCallDepth is used when VMOptions.MaxCallDepth is left at its
+// zero value: comfortably above any reasonable JS recursion depth, but
+// well short of actually overflowing Go's (much larger, but not
+// unlimited) goroutine stack.
+const defaultMaxCallDepth = 2000
+
+// NewVMWithOptions is NewVM with the defaults overridable via opts.
+func NewVMWithOptions(opts VMOptions) (vm VM) {
+	size := opts.CompileCacheSize
+	if size <= 0 {
+		size = defaultCompileCacheSize
+	}
+
+	vm.globalObject, vm.wellKnownSymbols = createGlobalObject()
+	vm.compileCache = newCompileCache(size)
+	vm.symbolRegistry = make(map[string]*JSSymbol)
+	vm.modules = make(map[string]*ModuleRecord)
+	vm.constantFoldingEnabled = opts.EnableConstantFolding
+	vm.maxCallDepth = opts.MaxCallDepth
+	if vm.maxCallDepth <= 0 {
+		vm.maxCallDepth = defaultMaxCallDepth
+	}
+	if opts.ModuleBaseDir != "" {
+		vm.loader = NewFSLoader(opts.ModuleBaseDir)
+	}
+	return
+}
+
+// compileCacheKey identifies a compiled script well enough that reusing
+// the cached CompiledScript for a different (source, strictness, scope)
+// triple would be observably wrong. sourceHash alone isn't enough: the
+// same source compiles differently under strict mode, and for a direct
+// eval, the compiled variable-resolution offsets depend on exactly which
+// names are visible in the caller's scope chain.
+type compileCacheKey struct {
+	sourceHash uint64
+	strict     bool
+	scopeShape string
+}
+
+// CompileCache memoizes CompiledScripts so repeated RunCompiled-based
+// execution of identical source doesn't reparse/recompile it every time.
+// It's a plain bounded LRU, same shape as any other fixed-size cache.
+type CompileCache struct {
+	maxEntries int
+	order      *list.List
+	entries    map[compileCacheKey]*list.Element
+}
+
+type compileCacheEntry struct {
+	key    compileCacheKey
+	script *CompiledScript
+}
+
+func newCompileCache(maxEntries int) *CompileCache {
+	return &CompileCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[compileCacheKey]*list.Element),
+	}
+}
+
+func (c *CompileCache) get(key compileCacheKey) (*CompiledScript, bool) {
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*compileCacheEntry).script, true
+}
+
+func (c *CompileCache) put(key compileCacheKey, script *CompiledScript) {
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*compileCacheEntry).script = script
+		c.order.MoveToFront(el)
+		return
+	}
+
+	el := c.order.PushFront(&compileCacheEntry{key: key, script: script})
+	c.entries[key] = el
+
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*compileCacheEntry).key)
+	}
+}
+
+func hashSource(src string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(src))
+	return h.Sum64()
+}
+
+// scopeShapeFingerprint returns the ordered, scope-by-scope list of
+// variable names visible through scope's lexical chain. It's the "scope
+// shape" half of a direct eval's cache key: direct eval compiles against
+// the calling scope, so two evals of the same source text with
+// differently-shaped enclosing scopes must not share a CompiledScript.
+// ObjectEnv scopes (the global object) are skipped, since indirect eval
+// and top-level scripts — the only things this package compiles so far —
+// never see one here; reaching this code with a direct-eval caller is
+// reserved for when eval() itself is implemented.
+func scopeShapeFingerprint(scope *Scope) string {
+	var levels []string
+	for s := scope; s != nil; s = s.parent {
+		denv, isDirect := s.env.(DirectEnv)
+		if !isDirect {
+			continue
+		}
+		names := make([]string, 0, len(denv))
+		for name := range denv {
+			names = append(names, name.String())
+		}
+		sort.Strings(names)
+		levels = append(levels, strings.Join(names, ","))
+	}
+	return strings.Join(levels, "|")
+}
+
+// PrecompileScript compiles src (read in full from path's contents by the
+// caller) and caches the result, so a later RunCompiled over the same
+// source and strictness reuses it instead of reparsing. Long-running
+// embedders that know which scripts they'll run repeatedly (e.g. the
+// test262 harness files) can call this once up front to warm the cache.
+func (vm *VM) PrecompileScript(path string, src string) (*CompiledScript, error) {
+	return vm.compileCached(path, src, isStrict(vm.curScope), "")
+}
+
+// compileCached is PrecompileScript's implementation, also the one
+// RunCompiledSource (see below) funnels through, parameterized by the
+// scope-shape fingerprint a future direct-eval call site would supply.
+func (vm *VM) compileCached(path string, src string, strict bool, scopeShape string) (*CompiledScript, error) {
+	key := compileCacheKey{sourceHash: hashSource(src), strict: strict, scopeShape: scopeShape}
+	if cached, ok := vm.compileCache.get(key); ok {
+		return cached, nil
+	}
+
+	program, err := ParseReader(path, strings.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	vm.optimizeProgram(program)
+
+	script, err := CompileProgram(program.File, program)
+	if err != nil {
+		return nil, err
+	}
+
+	vm.compileCache.put(key, script)
+	return script, nil
+}
+
+// RunCompiledSource is PrecompileScript+RunCompiled in one call: it warms
+// (or reuses) the cache entry for src and immediately executes it.
+func (vm *VM) RunCompiledSource(path string, src string) (JSValue, error) {
+	script, err := vm.PrecompileScript(path, src)
+	if err != nil {
+		return nil, err
+	}
+	return vm.RunCompiled(script)
+}
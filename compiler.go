@@ -0,0 +1,697 @@
+package modeledjs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	parserFile "github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/token"
+)
+
+// Op identifies one bytecode instruction understood by Interpreter.
+//
+// This is the first step of the impl-2 migration sketched out in this
+// package's notes: a flat instruction list executed on an explicit
+// operand stack, instead of runStmt/evalExpr recursing through the Go call
+// stack. The two evaluators are meant to coexist for a while — compile
+// only covers a subset of statement/expression forms so far, and anything
+// it doesn't recognize is reported as a compile error rather than
+// silently miscompiled, so callers can fall back to runStmt/evalExpr for
+// that program. Later changes grow the subset (and eventually retire the
+// tree-walker) without needing to redesign this instruction set.
+type Op uint8
+
+const (
+	// OpPushConst pushes Consts[Arg] onto the stack.
+	OpPushConst Op = iota
+	// OpLoadVar looks Name up through the current Scope and pushes its value.
+	OpLoadVar
+	// OpStoreVar assigns the top-of-stack value to Name (leaving it on the
+	// stack, matching JS assignment-expression semantics).
+	OpStoreVar
+	// OpPop discards the top-of-stack value.
+	OpPop
+	// OpBinary pops right then left and applies the token.Token stored in
+	// Arg, pushing the result.
+	OpBinary
+	// OpJump unconditionally sets pc to Arg.
+	OpJump
+	// OpJumpIfFalse pops a value; if it coerces to false, sets pc to Arg.
+	OpJumpIfFalse
+	// OpReturn pops the return value and unwinds the current Frame.
+	OpReturn
+	// OpYield pops a value and suspends the current Frame, handing the
+	// value back to whatever resumed the generator (see generator.go).
+	// No compileExpr case emits it yet: the otto AST this package parses
+	// has no YieldExpression node.
+	OpYield
+	// OpYieldDelegate is like OpYield but for `yield*`: it suspends once
+	// per value produced by the delegated-to iterable, rather than once
+	// overall. Reserved for when compileExpr gains a YieldExpression case.
+	OpYieldDelegate
+	// OpAwait suspends the current Frame until the awaited JSPromise
+	// settles, the same way OpYield suspends for a generator's caller.
+	// Reserved for when compileExpr gains an AwaitExpression case.
+	OpAwait
+	// OpDup duplicates the top-of-stack value.
+	OpDup
+	// OpGetProp pops an object and pushes its Name property (coercing the
+	// popped value to an object first, same as evalExpr's DotExpression
+	// case).
+	OpGetProp
+	// OpSetProp pops a value then an object, assigns the value to the
+	// object's Name property, and pushes the value back (matching
+	// doAssignment's DotExpression case).
+	OpSetProp
+	// OpGetElem pops a key then an object and pushes the result of a
+	// computed member access (obj[key]), with the same key-type handling
+	// as evalExpr's BracketExpression case.
+	OpGetElem
+	// OpSetElem pops a value, a key, then an object, assigns through the
+	// computed member, and pushes the value back.
+	OpSetElem
+	// OpCall pops Arg arguments, then a callee, then a this-subject (in
+	// that order from the top), and pushes the call's return value.
+	// compileExpr always pushes the subject ahead of the callee, even for
+	// a plain (non-method) call, where it's JSUndefined.
+	OpCall
+	// OpNew is OpCall without a this-subject: it pops Arg arguments then
+	// a constructor, invokes it with a fresh object as `this` (see
+	// evalExpr's NewExpression case), and pushes the result.
+	OpNew
+	// OpThrow pops a value and throws it as a ProgramException, the same
+	// as runStmt's ThrowStatement case.
+	OpThrow
+	// OpTryEnter pushes a handler onto the Interpreter's handler stack,
+	// recording Arg as the catch block's pc and the current operand
+	// stack depth to unwind to if the guarded region throws.
+	OpTryEnter
+	// OpTryLeave pops the handler pushed by the most recent OpTryEnter,
+	// run once the guarded region completes without throwing.
+	OpTryLeave
+	// OpDefineVar pops a value and defines it as Name in the current
+	// scope (DeclVar), the same as the tree-walker binding a catch
+	// clause's parameter.
+	OpDefineVar
+)
+
+// Instruction is one compiled bytecode op, carrying enough of its source
+// position to feed ProgramContext for stack traces.
+type Instruction struct {
+	Op   Op
+	Arg  int
+	Name Name
+	pos  parserFile.Position
+}
+
+// CompiledScript is the flat instruction list and constant pool produced
+// by compiling an *ast.Program. It's the unit Interpreter executes.
+type CompiledScript struct {
+	Ops    []Instruction
+	Consts []JSValue
+	file   *parserFile.File
+}
+
+// compiler lowers statements and expressions into Instructions. A
+// compiler is single-use: create one per CompiledScript.
+type compiler struct {
+	file          *parserFile.File
+	ops           []Instruction
+	consts        []JSValue
+	breakables    []*breakableCtx
+	pendingLabels []string
+}
+
+// breakableCtx tracks the break (and, for a loop, continue) jumps
+// emitted against one enclosing labeled statement, so they can be
+// patched once its end (and, for a loop, its condition) pc is known.
+// Every loop gets one, whether or not it's labeled; a plain block only
+// gets one when at least one label (from an enclosing LabelledStatement)
+// applies to it, since an unlabeled block isn't a valid break target.
+type breakableCtx struct {
+	labels    []string
+	isLoop    bool
+	breaks    []int
+	continues []int
+}
+
+// takePendingLabels returns and clears the labels accumulated by
+// compiling through zero or more nested *ast.LabelledStatement wrappers
+// (e.g. both "outer" and "inner" in `outer: inner: for (...) {}`), for
+// attachment to the loop or block statement they directly wrap.
+func (c *compiler) takePendingLabels() []string {
+	labels := c.pendingLabels
+	c.pendingLabels = nil
+	return labels
+}
+
+// findBreakable resolves an (unlabeled, for label == "") break to the
+// innermost enclosing loop or labeled block, or a labeled break to the
+// breakableCtx carrying that label.
+func (c *compiler) findBreakable(label string) (*breakableCtx, error) {
+	if label == "" {
+		if len(c.breakables) == 0 {
+			return nil, fmt.Errorf("compiler: break outside of a loop or labeled statement")
+		}
+		return c.breakables[len(c.breakables)-1], nil
+	}
+	for i := len(c.breakables) - 1; i >= 0; i-- {
+		for _, l := range c.breakables[i].labels {
+			if l == label {
+				return c.breakables[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("compiler: break label not found: %s", label)
+}
+
+// findContinuable is findBreakable's continue counterpart: continue can
+// only ever target a loop, whether reached as the innermost enclosing
+// one (label == "") or by label. A label that does resolve, but to a
+// non-loop statement, is the SyntaxError ECMAScript calls for ("continue
+// foo" where foo doesn't label an iteration statement).
+func (c *compiler) findContinuable(label string) (*breakableCtx, error) {
+	if label == "" {
+		for i := len(c.breakables) - 1; i >= 0; i-- {
+			if c.breakables[i].isLoop {
+				return c.breakables[i], nil
+			}
+		}
+		return nil, fmt.Errorf("compiler: continue outside of a loop")
+	}
+	for i := len(c.breakables) - 1; i >= 0; i-- {
+		for _, l := range c.breakables[i].labels {
+			if l != label {
+				continue
+			}
+			if !c.breakables[i].isLoop {
+				return nil, fmt.Errorf("compiler: continue label does not label a loop: %s", label)
+			}
+			return c.breakables[i], nil
+		}
+	}
+	return nil, fmt.Errorf("compiler: continue label not found: %s", label)
+}
+
+// CompileProgram lowers program into a CompiledScript, or returns an error
+// naming the first unsupported construct it finds. file must be the same
+// *parserFile.File the program was parsed from, since Instruction
+// positions are resolved against it.
+func CompileProgram(file *parserFile.File, program *ast.Program) (*CompiledScript, error) {
+	c := &compiler{file: file}
+	for _, stmt := range program.Body {
+		if err := c.compileStmt(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return &CompiledScript{Ops: c.ops, Consts: c.consts, file: file}, nil
+}
+
+// compileFunctionBody lowers fp's body for Invoke's bytecode fast path.
+// fp.body is always a *ast.BlockStatement for a JS function (makeFunction
+// relies on the same assumption to compute isStrict), so this just
+// compiles its statement list directly rather than going through
+// CompileProgram's *ast.Program wrapper.
+func compileFunctionBody(fp *FunctionPart) (*CompiledScript, error) {
+	block, isBlock := fp.body.(*ast.BlockStatement)
+	if !isBlock {
+		return nil, fmt.Errorf("compiler: function body is not a block statement: %T", fp.body)
+	}
+	if fp.file == nil {
+		return nil, fmt.Errorf("compiler: function has no source file attached")
+	}
+
+	c := &compiler{file: fp.file}
+	for _, stmt := range block.List {
+		if err := c.compileStmt(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return &CompiledScript{Ops: c.ops, Consts: c.consts, file: fp.file}, nil
+}
+
+func (c *compiler) position(idx parserFile.Idx) parserFile.Position {
+	if p := c.file.Position(idx); p != nil {
+		return *p
+	}
+	return parserFile.Position{}
+}
+
+func (c *compiler) emit(idx parserFile.Idx, op Op, arg int) int {
+	pos := len(c.ops)
+	c.ops = append(c.ops, Instruction{Op: op, Arg: arg, pos: c.position(idx)})
+	return pos
+}
+
+func (c *compiler) emitNamed(idx parserFile.Idx, op Op, name Name) int {
+	pos := len(c.ops)
+	c.ops = append(c.ops, Instruction{Op: op, Name: name, pos: c.position(idx)})
+	return pos
+}
+
+func (c *compiler) addConst(value JSValue) int {
+	c.consts = append(c.consts, value)
+	return len(c.consts) - 1
+}
+
+func (c *compiler) patchJump(at int, target int) {
+	c.ops[at].Arg = target
+}
+
+func (c *compiler) compileStmt(stmt ast.Statement) error {
+	switch s := stmt.(type) {
+	case *ast.EmptyStatement:
+		return nil
+
+	case *ast.ExpressionStatement:
+		if err := c.compileExpr(s.Expression); err != nil {
+			return err
+		}
+		c.emit(s.Idx0(), OpPop, 0)
+		return nil
+
+	case *ast.VariableStatement:
+		for _, item := range s.List {
+			if err := c.compileExpr(item); err != nil {
+				return err
+			}
+			c.emit(item.Idx0(), OpPop, 0)
+		}
+		return nil
+
+	case *ast.ReturnStatement:
+		if s.Argument != nil {
+			if err := c.compileExpr(s.Argument); err != nil {
+				return err
+			}
+		} else {
+			c.emit(s.Idx0(), OpPushConst, c.addConst(JSUndefined{}))
+		}
+		c.emit(s.Idx0(), OpReturn, 0)
+		return nil
+
+	case *ast.IfStatement:
+		if err := c.compileExpr(s.Test); err != nil {
+			return err
+		}
+		jumpToAlt := c.emit(s.Idx0(), OpJumpIfFalse, 0)
+		if err := c.compileStmt(s.Consequent); err != nil {
+			return err
+		}
+		if s.Alternate == nil {
+			c.patchJump(jumpToAlt, len(c.ops))
+			return nil
+		}
+		jumpToEnd := c.emit(s.Idx0(), OpJump, 0)
+		c.patchJump(jumpToAlt, len(c.ops))
+		if err := c.compileStmt(s.Alternate); err != nil {
+			return err
+		}
+		c.patchJump(jumpToEnd, len(c.ops))
+		return nil
+
+	case *ast.BlockStatement:
+		// Only a labeled block needs a breakableCtx: `break label;` must
+		// be able to find it, but an unlabeled block isn't itself a
+		// valid break target (only its enclosing loop/labeled statement
+		// is).
+		labels := c.takePendingLabels()
+		var ctx *breakableCtx
+		if len(labels) > 0 {
+			ctx = &breakableCtx{labels: labels}
+			c.breakables = append(c.breakables, ctx)
+		}
+
+		for _, inner := range s.List {
+			if err := c.compileStmt(inner); err != nil {
+				return err
+			}
+		}
+
+		if ctx != nil {
+			endPC := len(c.ops)
+			for _, at := range ctx.breaks {
+				c.patchJump(at, endPC)
+			}
+			c.breakables = c.breakables[:len(c.breakables)-1]
+		}
+		return nil
+
+	case *ast.LabelledStatement:
+		c.pendingLabels = append(c.pendingLabels, s.Label.Name)
+		err := c.compileStmt(s.Statement)
+		// compileStmt of the wrapped statement (a loop or block) always
+		// consumes pendingLabels via takePendingLabels; for any other
+		// wrapped statement (label has no break/continue target there)
+		// it's left untouched, so clear it out here either way.
+		c.pendingLabels = nil
+		return err
+
+	case *ast.WhileStatement:
+		ctx := &breakableCtx{labels: c.takePendingLabels(), isLoop: true}
+		c.breakables = append(c.breakables, ctx)
+
+		condPC := len(c.ops)
+		if err := c.compileExpr(s.Test); err != nil {
+			return err
+		}
+		exitJump := c.emit(s.Idx0(), OpJumpIfFalse, 0)
+		if err := c.compileStmt(s.Body); err != nil {
+			return err
+		}
+		c.emit(s.Idx0(), OpJump, condPC)
+		endPC := len(c.ops)
+		c.patchJump(exitJump, endPC)
+
+		for _, at := range ctx.breaks {
+			c.patchJump(at, endPC)
+		}
+		for _, at := range ctx.continues {
+			c.patchJump(at, condPC)
+		}
+		c.breakables = c.breakables[:len(c.breakables)-1]
+		return nil
+
+	case *ast.ForStatement:
+		ctx := &breakableCtx{labels: c.takePendingLabels(), isLoop: true}
+		c.breakables = append(c.breakables, ctx)
+
+		if s.Initializer != nil {
+			// The parser always wraps a for-loop's initializer in a
+			// SequenceExpression (even a single `var` declarator), so
+			// unwrap it here rather than teaching compileExpr a general
+			// comma-operator case just for this.
+			if seq, ok := s.Initializer.(*ast.SequenceExpression); ok {
+				for _, item := range seq.Sequence {
+					if err := c.compileExpr(item); err != nil {
+						return err
+					}
+					c.emit(item.Idx0(), OpPop, 0)
+				}
+			} else {
+				if err := c.compileExpr(s.Initializer); err != nil {
+					return err
+				}
+				c.emit(s.Initializer.Idx0(), OpPop, 0)
+			}
+		}
+
+		condPC := len(c.ops)
+		var exitJump int
+		if s.Test != nil {
+			if err := c.compileExpr(s.Test); err != nil {
+				return err
+			}
+			exitJump = c.emit(s.Idx0(), OpJumpIfFalse, 0)
+		}
+		if err := c.compileStmt(s.Body); err != nil {
+			return err
+		}
+		updatePC := len(c.ops)
+		if s.Update != nil {
+			if err := c.compileExpr(s.Update); err != nil {
+				return err
+			}
+			c.emit(s.Update.Idx0(), OpPop, 0)
+		}
+		c.emit(s.Idx0(), OpJump, condPC)
+		endPC := len(c.ops)
+		if s.Test != nil {
+			c.patchJump(exitJump, endPC)
+		}
+
+		for _, at := range ctx.breaks {
+			c.patchJump(at, endPC)
+		}
+		for _, at := range ctx.continues {
+			c.patchJump(at, updatePC)
+		}
+		c.breakables = c.breakables[:len(c.breakables)-1]
+		return nil
+
+	case *ast.BranchStatement:
+		label := ""
+		if s.Label != nil {
+			label = s.Label.Name
+		}
+		if s.Token == token.BREAK {
+			ctx, err := c.findBreakable(label)
+			if err != nil {
+				return err
+			}
+			at := c.emit(s.Idx0(), OpJump, 0)
+			ctx.breaks = append(ctx.breaks, at)
+			return nil
+		}
+		ctx, err := c.findContinuable(label)
+		if err != nil {
+			return err
+		}
+		at := c.emit(s.Idx0(), OpJump, 0)
+		ctx.continues = append(ctx.continues, at)
+		return nil
+
+	case *ast.ThrowStatement:
+		if err := c.compileExpr(s.Argument); err != nil {
+			return err
+		}
+		c.emit(s.Idx0(), OpThrow, 0)
+		return nil
+
+	case *ast.TryStatement:
+		if s.Finally != nil {
+			return fmt.Errorf("compiler: unsupported statement: try/finally")
+		}
+		if s.Catch == nil {
+			return fmt.Errorf("compiler: unsupported statement: try without catch")
+		}
+
+		enterAt := c.emit(s.Idx0(), OpTryEnter, 0)
+		if err := c.compileStmt(s.Body); err != nil {
+			return err
+		}
+		c.emit(s.Idx0(), OpTryLeave, 0)
+		jumpOverCatch := c.emit(s.Idx0(), OpJump, 0)
+
+		catchPC := len(c.ops)
+		c.patchJump(enterAt, catchPC)
+		c.emitNamed(s.Catch.Parameter.Idx0(), OpDefineVar, NameStr(s.Catch.Parameter.Name))
+		if err := c.compileStmt(s.Catch.Body); err != nil {
+			return err
+		}
+		c.patchJump(jumpOverCatch, len(c.ops))
+		return nil
+
+	default:
+		return fmt.Errorf("compiler: unsupported statement: %T", stmt)
+	}
+}
+
+func (c *compiler) compileExpr(expr ast.Expression) error {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		// See the matching comment in evalExpr: an int64 Value just means
+		// otto's parser could represent the literal exactly as an integer,
+		// not that it's a BigInt — only a Literal ending in "n" means that.
+		if strings.HasSuffix(e.Literal, "n") {
+			spec, ok := e.Value.(int64)
+			if !ok {
+				return fmt.Errorf("compiler: invalid bigint literal value: %#v", e.Value)
+			}
+			c.emit(e.Idx0(), OpPushConst, c.addConst(bigIntFromInt64(spec)))
+			return nil
+		}
+		switch spec := e.Value.(type) {
+		case float64:
+			c.emit(e.Idx0(), OpPushConst, c.addConst(JSNumber(spec)))
+		case int64:
+			c.emit(e.Idx0(), OpPushConst, c.addConst(JSNumber(spec)))
+		default:
+			return fmt.Errorf("compiler: invalid number literal value: %#v", e.Value)
+		}
+		return nil
+
+	case *ast.StringLiteral:
+		c.emit(e.Idx0(), OpPushConst, c.addConst(JSString(e.Value)))
+		return nil
+
+	case *ast.BooleanLiteral:
+		c.emit(e.Idx0(), OpPushConst, c.addConst(JSBoolean(e.Value)))
+		return nil
+
+	case *ast.NullLiteral:
+		c.emit(e.Idx0(), OpPushConst, c.addConst(JSNull{}))
+		return nil
+
+	case *ast.Identifier:
+		c.emitNamed(e.Idx0(), OpLoadVar, NameStr(e.Name))
+		return nil
+
+	case *ast.VariableExpression:
+		// A var declarator (e.g. the `x = 1` in `var x = 1;`, or one
+		// item of a for-loop's `var` initializer). hoistVars already
+		// defined the name as undefined before this point, so this only
+		// needs to assign the initializer. OpDefineVar alone can't do that
+		// inside a function, where DirectEnv.defineVar discards a
+		// redefinition, so an initializer's value is also stored with
+		// OpStoreVar.
+		if e.Initializer != nil {
+			if err := c.compileExpr(e.Initializer); err != nil {
+				return err
+			}
+		} else {
+			c.emit(e.Idx0(), OpPushConst, c.addConst(JSUndefined{}))
+		}
+		c.emit(e.Idx0(), OpDup, 0)
+		c.emitNamed(e.Idx0(), OpDefineVar, NameStr(e.Name))
+		if e.Initializer != nil {
+			c.emitNamed(e.Idx0(), OpStoreVar, NameStr(e.Name))
+		}
+		return nil
+
+	case *ast.UnaryExpression:
+		switch e.Operator {
+		case token.INCREMENT, token.DECREMENT:
+			// Only an identifier target is handled here (the common
+			// case, including every for-loop counter); anything else
+			// (e.g. obj.prop++) reports unsupported so the whole program
+			// falls back to the tree-walker, same as an unsupported
+			// assignment target above.
+			ident, ok := e.Operand.(*ast.Identifier)
+			if !ok {
+				return fmt.Errorf("compiler: unsupported increment/decrement target: %T", e.Operand)
+			}
+			op := token.PLUS
+			if e.Operator == token.DECREMENT {
+				op = token.MINUS
+			}
+			c.emitNamed(e.Idx0(), OpLoadVar, NameStr(ident.Name))
+			if e.Postfix {
+				c.emit(e.Idx0(), OpDup, 0)
+			}
+			c.emit(e.Idx0(), OpPushConst, c.addConst(JSNumber(1)))
+			c.emit(e.Idx0(), OpBinary, int(op))
+			c.emitNamed(e.Idx0(), OpStoreVar, NameStr(ident.Name))
+			if e.Postfix {
+				c.emit(e.Idx0(), OpPop, 0)
+			}
+			return nil
+		default:
+			return fmt.Errorf("compiler: unsupported unary operator: %s", e.Operator)
+		}
+
+	case *ast.BinaryExpression:
+		switch e.Operator {
+		case token.STRICT_EQUAL, token.STRICT_NOT_EQUAL, token.EQUAL, token.NOT_EQUAL,
+			token.PLUS, token.MINUS, token.MULTIPLY, token.SLASH,
+			token.LESS, token.LESS_OR_EQUAL, token.GREATER_OR_EQUAL, token.GREATER:
+			if err := c.compileExpr(e.Left); err != nil {
+				return err
+			}
+			if err := c.compileExpr(e.Right); err != nil {
+				return err
+			}
+			c.emit(e.Idx0(), OpBinary, int(e.Operator))
+			return nil
+		default:
+			return fmt.Errorf("compiler: unsupported binary operator: %s", e.Operator)
+		}
+
+	case *ast.AssignExpression:
+		if e.Operator != token.ASSIGN {
+			return fmt.Errorf("compiler: unsupported assignment operator: %s", e.Operator)
+		}
+		switch target := e.Left.(type) {
+		case *ast.Identifier:
+			if err := c.compileExpr(e.Right); err != nil {
+				return err
+			}
+			c.emitNamed(e.Idx0(), OpStoreVar, NameStr(target.Name))
+			return nil
+
+		case *ast.DotExpression:
+			if err := c.compileExpr(target.Left); err != nil {
+				return err
+			}
+			if err := c.compileExpr(e.Right); err != nil {
+				return err
+			}
+			c.emitNamed(e.Idx0(), OpSetProp, NameStr(target.Identifier.Name))
+			return nil
+
+		case *ast.BracketExpression:
+			if err := c.compileExpr(target.Left); err != nil {
+				return err
+			}
+			if err := c.compileExpr(target.Member); err != nil {
+				return err
+			}
+			if err := c.compileExpr(e.Right); err != nil {
+				return err
+			}
+			c.emit(e.Idx0(), OpSetElem, 0)
+			return nil
+
+		default:
+			return fmt.Errorf("compiler: unsupported assignment target: %T", e.Left)
+		}
+
+	case *ast.DotExpression:
+		if err := c.compileExpr(e.Left); err != nil {
+			return err
+		}
+		c.emitNamed(e.Idx0(), OpGetProp, NameStr(e.Identifier.Name))
+		return nil
+
+	case *ast.BracketExpression:
+		if err := c.compileExpr(e.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpr(e.Member); err != nil {
+			return err
+		}
+		c.emit(e.Idx0(), OpGetElem, 0)
+		return nil
+
+	case *ast.CallExpression:
+		if calleeDot, isDot := e.Callee.(*ast.DotExpression); isDot {
+			// method call: the subject doubles as both `this` and the
+			// object the method is looked up on, so evaluate it once and
+			// OpDup the copy OpGetProp consumes (same split evalExpr's
+			// CallExpression case makes for calleeDot).
+			if err := c.compileExpr(calleeDot.Left); err != nil {
+				return err
+			}
+			c.emit(e.Idx0(), OpDup, 0)
+			c.emitNamed(calleeDot.Idx0(), OpGetProp, NameStr(calleeDot.Identifier.Name))
+		} else {
+			c.emit(e.Idx0(), OpPushConst, c.addConst(JSUndefined{}))
+			if err := c.compileExpr(e.Callee); err != nil {
+				return err
+			}
+		}
+		for _, argExpr := range e.ArgumentList {
+			if err := c.compileExpr(argExpr); err != nil {
+				return err
+			}
+		}
+		c.emit(e.Idx0(), OpCall, len(e.ArgumentList))
+		return nil
+
+	case *ast.NewExpression:
+		if err := c.compileExpr(e.Callee); err != nil {
+			return err
+		}
+		for _, argExpr := range e.ArgumentList {
+			if err := c.compileExpr(argExpr); err != nil {
+				return err
+			}
+		}
+		c.emit(e.Idx0(), OpNew, len(e.ArgumentList))
+		return nil
+
+	default:
+		return fmt.Errorf("compiler: unsupported expression: %T", expr)
+	}
+}
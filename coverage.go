@@ -0,0 +1,255 @@
+package modeledjs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/robertkrimen/otto/ast"
+	parserFile "github.com/robertkrimen/otto/file"
+)
+
+// CoverageKey identifies one source range recorded by a Coverage
+// profile: the [start, end) of a statement or expression the tree-
+// walker ran, or the single point position of an Instruction the
+// bytecode Interpreter executed (Instruction carries no end position —
+// see RunCompiled — so Start and End are equal there).
+type CoverageKey struct {
+	File                string
+	StartLine, StartCol int
+	EndLine, EndCol     int
+}
+
+// Coverage accumulates execution-hit counts across however many VMs
+// share it, keyed by CoverageKey. Safe for concurrent use: EnableCoverage
+// lets every worker in a pool like runMany's record into one shared
+// Coverage without each needing its own merge step.
+type Coverage struct {
+	mu   sync.Mutex
+	hits map[CoverageKey]uint64
+}
+
+// NewCoverage returns an empty profile, ready to attach to one or more
+// VMs via VM.EnableCoverage.
+func NewCoverage() *Coverage {
+	return &Coverage{hits: make(map[CoverageKey]uint64)}
+}
+
+// record increments the hit count for the range [start, end) in file.
+// A zero-valued start (no position information, e.g. a synthetic node
+// introduced by the optimizer) is silently ignored.
+func (cov *Coverage) record(file string, start, end parserFile.Position) {
+	if file == "" || start.Line == 0 {
+		return
+	}
+	key := CoverageKey{file, start.Line, start.Column, end.Line, end.Column}
+	cov.mu.Lock()
+	cov.hits[key]++
+	cov.mu.Unlock()
+}
+
+// Merge folds other's hit counts into cov, for combining profiles
+// gathered by separate Coverage instances (e.g. one per test262 harness
+// process) into a single report.
+func (cov *Coverage) Merge(other *Coverage) {
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	cov.mu.Lock()
+	defer cov.mu.Unlock()
+	for k, n := range other.hits {
+		cov.hits[k] += n
+	}
+}
+
+// WriteProfile writes cov as one line per recorded source range,
+//
+//	file:startLine.startCol,endLine.endCol hits
+//
+// sorted by file then position. This mirrors the layout golang.org/x/
+// tools' cmd/cover uses for its block profiles, minus the mode header
+// and numStmt column: a CoverageKey is already one AST node or bytecode
+// instruction, not a merged basic block, so there's nothing to count
+// statements of.
+func (cov *Coverage) WriteProfile(w io.Writer) error {
+	cov.mu.Lock()
+	defer cov.mu.Unlock()
+
+	keys := make([]CoverageKey, 0, len(cov.hits))
+	for k := range cov.hits {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		if a.StartCol != b.StartCol {
+			return a.StartCol < b.StartCol
+		}
+		if a.EndLine != b.EndLine {
+			return a.EndLine < b.EndLine
+		}
+		return a.EndCol < b.EndCol
+	})
+
+	for _, k := range keys {
+		_, err := fmt.Fprintf(w, "%s:%d.%d,%d.%d %d\n",
+			k.File, k.StartLine, k.StartCol, k.EndLine, k.EndCol, cov.hits[k])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProfileEntry is one parsed line of a coverage profile written by
+// Coverage.WriteProfile.
+type ProfileEntry struct {
+	CoverageKey
+	Hits uint64
+}
+
+// ReadProfile parses the text format WriteProfile writes, for tools
+// like cmd/coverreport that consume a profile without linking against
+// the VM that produced it.
+func ReadProfile(r io.Reader) ([]ProfileEntry, error) {
+	var entries []ProfileEntry
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		file, rest, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("coverage profile: invalid line %q: expected <file>:<range> <hits>", line)
+		}
+
+		entry := ProfileEntry{CoverageKey: CoverageKey{File: file}}
+		_, err := fmt.Sscanf(rest, "%d.%d,%d.%d %d",
+			&entry.StartLine, &entry.StartCol, &entry.EndLine, &entry.EndCol, &entry.Hits)
+		if err != nil {
+			return nil, fmt.Errorf("coverage profile: invalid line %q: %w", line, err)
+		}
+
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CoverageStats is how many AST nodes of some grouping (a file, or a Go
+// type like "*ast.IfStatement") AnalyzeCoverage saw in total, and how
+// many of them had at least one profiled hit fall inside their range.
+type CoverageStats struct {
+	Hit, Total int
+}
+
+// Ratio returns stats.Hit / stats.Total, or 0 if Total is 0.
+func (stats CoverageStats) Ratio() float64 {
+	if stats.Total == 0 {
+		return 0
+	}
+	return float64(stats.Hit) / float64(stats.Total)
+}
+
+// AnalyzeCoverage re-parses every source file entries references and
+// walks its AST, bucketing every node by its file and by its Go type
+// name (e.g. "*ast.IfStatement"), and checking whether any entry's start
+// position falls inside the node's own [Idx0, Idx1) range. entries alone
+// can't answer "what fraction of if-statements ran": a Coverage profile
+// only ever records positions that *were* reached, with no catalogue of
+// what else exists in the source to compare against, so cmd/coverreport
+// needs this to turn raw hits into a ratio.
+func AnalyzeCoverage(entries []ProfileEntry) (byFile, byKind map[string]CoverageStats, err error) {
+	hitsByFile := make(map[string][]ProfileEntry)
+	for _, e := range entries {
+		hitsByFile[e.File] = append(hitsByFile[e.File], e)
+	}
+
+	byFile = make(map[string]CoverageStats)
+	byKind = make(map[string]CoverageStats)
+
+	for filename, hits := range hitsByFile {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s: %w", filename, err)
+		}
+		program, err := ParseReader(filename, bytes.NewReader(src))
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing %s: %w", filename, err)
+		}
+
+		walker := &coverageWalker{file: program.File, hits: hits, byKind: byKind}
+		ast.Walk(walker, program)
+		byFile[filename] = walker.fileStats
+	}
+
+	return byFile, byKind, nil
+}
+
+// coverageWalker is the ast.Visitor AnalyzeCoverage drives over one
+// file's AST, accumulating fileStats (this file's totals) and byKind
+// (shared across every file AnalyzeCoverage walks) as it goes.
+type coverageWalker struct {
+	file      *parserFile.File
+	hits      []ProfileEntry
+	byKind    map[string]CoverageStats
+	fileStats CoverageStats
+}
+
+func (w *coverageWalker) Enter(node ast.Node) ast.Visitor {
+	startp := w.file.Position(node.Idx0())
+	endp := w.file.Position(node.Idx1())
+	if startp == nil || endp == nil {
+		return w
+	}
+
+	hit := false
+	for _, h := range w.hits {
+		if positionWithin(h.StartLine, h.StartCol, *startp, *endp) {
+			hit = true
+			break
+		}
+	}
+
+	kind := reflect.TypeOf(node).String()
+	stats := w.byKind[kind]
+	stats.Total++
+	w.fileStats.Total++
+	if hit {
+		stats.Hit++
+		w.fileStats.Hit++
+	}
+	w.byKind[kind] = stats
+
+	return w
+}
+
+func (w *coverageWalker) Exit(ast.Node) {}
+
+// positionWithin reports whether (line, col) falls within [start, end),
+// comparing lexicographically on (line, col) the way Position's own
+// fields order a point in the source.
+func positionWithin(line, col int, start, end parserFile.Position) bool {
+	if line < start.Line || (line == start.Line && col < start.Column) {
+		return false
+	}
+	if line > end.Line || (line == end.Line && col >= end.Column) {
+		return false
+	}
+	return true
+}
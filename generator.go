@@ -0,0 +1,98 @@
+package modeledjs
+
+// AsyncContextTracker lets a Go embedder propagate ambient state (tracing
+// spans, request-scoped values, ...) across generator/async suspension
+// points, since those points return control to Go and may resume on a
+// different goroutine or after arbitrary other work has run. Grab is
+// called just before a Frame suspends, Resumed just before it continues
+// with whatever Grab returned, and Exited once it has suspended or
+// finished. A VM with no tracker installed (the default) skips all three.
+type AsyncContextTracker interface {
+	Grab() any
+	Resumed(ctx any)
+	Exited()
+}
+
+// SetAsyncContextTracker installs tracker, or clears it if tracker is nil.
+func (vm *VM) SetAsyncContextTracker(tracker AsyncContextTracker) {
+	vm.asyncTracker = tracker
+}
+
+// Generator holds one suspended activation of a generator function: the
+// interpreter that's running it, the frame it suspended at (pc, operand
+// stack contents, scope), and whether it has already run to completion.
+// Unlike a plain Invoke, a generator's state survives between calls
+// instead of unwinding when Go returns, which is why it needs the
+// Interpreter/Frame split from interpreter.go rather than runStmt/evalExpr.
+type Generator struct {
+	interp  *Interpreter
+	frame   *Frame // nil once done
+	started bool
+	done    bool
+
+	// asyncCtx is what the VM's AsyncContextTracker returned from Grab
+	// when this generator last suspended; Next hands it to Resumed.
+	asyncCtx any
+}
+
+// NewGenerator starts a suspended generator over script, to be driven by
+// repeated calls to Next. script is expected to have come from compiling
+// a generator function's body, though compileExpr has no YieldExpression
+// case yet (see compiler.go), so nothing produces such a script today.
+func NewGenerator(vm *VM, script *CompiledScript, scope *Scope) *Generator {
+	return &Generator{
+		interp: &Interpreter{vm: vm, script: script},
+		frame:  &Frame{code: script.Ops, scope: scope},
+	}
+}
+
+// iterResult builds the {value, done} object the generator/iterator
+// protocol requires .next()/.return()/.throw() to return.
+func iterResult(value JSValue, done bool) JSObject {
+	obj := NewJSObject(&ProtoObject)
+	obj.SetProperty(NameStr("value"), value, nil)
+	obj.SetProperty(NameStr("done"), JSBoolean(done), nil)
+	return obj
+}
+
+// Next resumes g, pushing sent as the value of the `yield` expression
+// that suspended it. Per the generator protocol, the argument to the
+// very first call is discarded, since there's no pending `yield` waiting
+// for a value yet.
+func (g *Generator) Next(sent JSValue) (JSObject, error) {
+	if g.done {
+		return iterResult(JSUndefined{}, true), nil
+	}
+
+	tracker := g.interp.vm.asyncTracker
+	if tracker != nil {
+		if g.started {
+			tracker.Resumed(g.asyncCtx)
+		}
+		defer tracker.Exited()
+	}
+
+	if g.started {
+		g.interp.push(sent)
+	}
+	g.started = true
+
+	value, suspended, err := g.interp.run(*g.frame)
+	if err != nil {
+		g.done = true
+		g.frame = nil
+		return JSObject{}, err
+	}
+
+	if suspended == nil {
+		g.done = true
+		g.frame = nil
+		return iterResult(value, true), nil
+	}
+
+	g.frame = suspended
+	if tracker != nil {
+		g.asyncCtx = tracker.Grab()
+	}
+	return iterResult(value, false), nil
+}
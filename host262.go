@@ -0,0 +1,89 @@
+package modeledjs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Host262Options configures InstallHost262.
+type Host262Options struct {
+	// OnAsyncDone, if non-nil, is sent on whenever the installed $DONE
+	// function is called (as harness/doneprintHandle.js arranges for
+	// `async` test262 cases): nil on success, or an error describing
+	// whatever value the test passed to $DONE.
+	OnAsyncDone chan error
+}
+
+// InstallHost262 defines the `$262` host-defined object that the test262
+// harness (agents, realms, doneprintHandle.js) expects to find on the
+// global object. Only the members actually exercised by the corpus so far
+// are implemented; everything else throws Test262Error so a case that
+// needs it fails loudly instead of silently misbehaving.
+func (vm *VM) InstallHost262(opts Host262Options) {
+	host := newHost262Object(vm)
+	vm.globalObject.SetProperty(NameStr("$262"), &host, nil)
+
+	if opts.OnAsyncDone != nil {
+		done := NewNativeFunction([]string{"error"}, func(_ *VM, _ JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+			var arg JSValue = JSUndefined{}
+			if len(args) > 0 {
+				arg = args[0]
+			}
+			if _, isUndef := arg.(JSUndefined); isUndef {
+				opts.OnAsyncDone <- nil
+			} else {
+				opts.OnAsyncDone <- fmt.Errorf("$DONE called with: %#v", arg)
+			}
+			return JSUndefined{}, nil
+		})
+		vm.globalObject.SetProperty(NameStr("$DONE"), &done, nil)
+	}
+}
+
+func newHost262Object(vm *VM) JSObject {
+	host := NewJSObject(&ProtoObject)
+
+	host.SetProperty(NameStr("global"), &vm.globalObject, nil)
+
+	createRealm := NewNativeFunction(nil, func(_ *VM, _ JSValue, _ []JSValue, _ CallFlags) (JSValue, error) {
+		realmVM := NewVM()
+		realmVM.InstallHost262(Host262Options{})
+		return realmVM.globalObject.GetOwnProperty(NameStr("$262"), &realmVM)
+	})
+	host.SetProperty(NameStr("createRealm"), &createRealm, nil)
+
+	evalScript := NewNativeFunction([]string{"src"}, func(callerVM *VM, _ JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		if len(args) == 0 {
+			return JSUndefined{}, nil
+		}
+		src, err := callerVM.coerceToString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := vm.RunScriptReader("<evalScript>", strings.NewReader(string(src))); err != nil {
+			return nil, err
+		}
+		return JSUndefined{}, nil
+	})
+	host.SetProperty(NameStr("evalScript"), &evalScript, nil)
+
+	detachArrayBuffer := notImplementedHost262("detachArrayBuffer")
+	host.SetProperty(NameStr("detachArrayBuffer"), &detachArrayBuffer, nil)
+
+	agent := NewJSObject(&ProtoObject)
+	for _, method := range []string{"start", "broadcast", "getReport", "sleep", "monotonicNow", "receiveBroadcast", "report", "leaving"} {
+		fn := notImplementedHost262("agent." + method)
+		agent.SetProperty(NameStr(method), &fn, nil)
+	}
+	host.SetProperty(NameStr("agent"), &agent, nil)
+
+	return host
+}
+
+// notImplementedHost262 builds a native function that always throws
+// Test262Error, for the slice of the $262 API this VM doesn't back yet.
+func notImplementedHost262(name string) JSObject {
+	return NewNativeFunction(nil, func(callerVM *VM, _ JSValue, _ []JSValue, _ CallFlags) (JSValue, error) {
+		return nil, callerVM.ThrowError("Test262Error", fmt.Sprintf("$262.%s is not implemented", name))
+	})
+}
@@ -0,0 +1,355 @@
+package modeledjs
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/robertkrimen/otto/token"
+)
+
+// Frame is one activation of RunCompiled: the code it's executing, its
+// program counter, and the base of its region of the interpreter's
+// evalStack. Only one Frame exists today (CompiledScript has no calls
+// into other compiled code yet), but keeping the type separate from
+// Interpreter now avoids a reshuffle once OpCall needs to push new ones.
+type Frame struct {
+	code      []Instruction
+	pc        int
+	stackBase int
+	scope     *Scope
+}
+
+// Interpreter executes a CompiledScript on an explicit operand stack,
+// rather than recursing through runStmt/evalExpr on the Go call stack.
+type Interpreter struct {
+	vm        *VM
+	script    *CompiledScript
+	evalStack []JSValue
+	frames    []Frame
+	handlers  []tryHandler
+}
+
+// tryHandler is what OpTryEnter pushes: where to resume (the compiled
+// catch block) and how much of the operand stack to discard if the
+// guarded region throws before reaching the matching OpTryLeave.
+type tryHandler struct {
+	catchPC    int
+	stackDepth int
+}
+
+// RunCompiled executes script against the VM's current scope, the same
+// entry point the compile cache's RunCompiledSource calls into. It does not push a new top-level scope the way runProgram does —
+// callers that need one should set vm.curScope first, matching how
+// RunScriptReader itself only calls runProgram from a clean VM.
+func (vm *VM) RunCompiled(script *CompiledScript) (JSValue, error) {
+	vm.synCtx.PushFile(script.file)
+	defer vm.synCtx.PopFile(script.file)
+
+	interp := &Interpreter{vm: vm, script: script}
+	value, suspended, err := interp.run(Frame{code: script.Ops, scope: vm.curScope})
+	if err != nil {
+		return nil, err
+	}
+	if suspended != nil {
+		// OpYield/OpAwait outside of a Generator/async activation: the
+		// compiler doesn't emit either yet, so this is unreachable today.
+		return nil, vm.ThrowError("SyntaxError", "yield/await used outside of a generator or async function")
+	}
+	return value, nil
+}
+
+func (interp *Interpreter) push(v JSValue) {
+	interp.evalStack = append(interp.evalStack, v)
+}
+
+func (interp *Interpreter) pop() JSValue {
+	last := len(interp.evalStack) - 1
+	v := interp.evalStack[last]
+	interp.evalStack = interp.evalStack[:last]
+	return v
+}
+
+// run executes frame until it returns (OpReturn), suspends (OpYield), or
+// fails. A non-nil *Frame return means the frame suspended: the caller
+// (Generator.Next, today) owns resuming it later by calling run again
+// with that same Frame value.
+func (interp *Interpreter) run(frame Frame) (JSValue, *Frame, error) {
+	vm := interp.vm
+	saveScope := vm.curScope
+	vm.curScope = frame.scope
+	defer func() { vm.curScope = saveScope }()
+
+	for frame.pc < len(frame.code) {
+		instr := frame.code[frame.pc]
+		frame.pc++
+
+		if vm.coverage != nil {
+			vm.coverage.record(instr.pos.Filename, instr.pos, instr.pos)
+		}
+
+		var stepErr error
+
+		switch instr.Op {
+		case OpPushConst:
+			interp.push(interp.constAt(frame, instr.Arg))
+
+		case OpPop:
+			interp.pop()
+
+		case OpDup:
+			interp.push(interp.evalStack[len(interp.evalStack)-1])
+
+		case OpLoadVar:
+			value, found := vm.curScope.env.lookupVar(vm.curScope, instr.Name)
+			if !found {
+				stepErr = vm.ThrowError("NameError", fmt.Sprintf("undefined variable: %s", instr.Name))
+			} else {
+				interp.push(value)
+			}
+
+		case OpStoreVar:
+			value := interp.pop()
+			if stepErr = vm.curScope.env.setVar(vm.curScope, instr.Name, value, vm); stepErr == nil {
+				interp.push(value)
+			}
+
+		case OpDefineVar:
+			value := interp.pop()
+			vm.curScope.env.defineVar(vm.curScope, DeclVar, instr.Name, value)
+
+		case OpBinary:
+			right := interp.pop()
+			left := interp.pop()
+			var value JSValue
+			value, stepErr = interp.binaryOp(token.Token(instr.Arg), left, right)
+			if stepErr == nil {
+				interp.push(value)
+			}
+
+		case OpGetProp:
+			obj, err := vm.coerceToObject(interp.pop())
+			if err != nil {
+				stepErr = err
+				break
+			}
+			var value JSValue
+			value, stepErr = obj.GetProperty(instr.Name, vm)
+			if stepErr == nil {
+				interp.push(value)
+			}
+
+		case OpSetProp:
+			value := interp.pop()
+			obj, err := vm.coerceToObject(interp.pop())
+			if err != nil {
+				stepErr = err
+				break
+			}
+			if stepErr = obj.SetProperty(instr.Name, value, vm); stepErr == nil {
+				interp.push(value)
+			}
+
+		case OpGetElem:
+			key := interp.pop()
+			obj, err := vm.coerceToObject(interp.pop())
+			if err != nil {
+				stepErr = err
+				break
+			}
+			var value JSValue
+			value, stepErr = getElem(vm, obj, key)
+			if stepErr == nil {
+				interp.push(value)
+			}
+
+		case OpSetElem:
+			value := interp.pop()
+			key := interp.pop()
+			obj, err := vm.coerceToObject(interp.pop())
+			if err != nil {
+				stepErr = err
+				break
+			}
+			if stepErr = setElem(vm, obj, key, value); stepErr == nil {
+				interp.push(value)
+			}
+
+		case OpCall:
+			argc := instr.Arg
+			args := make([]JSValue, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = interp.pop()
+			}
+			calleeObj, err := vm.coerceToObject(interp.pop())
+			subject := interp.pop()
+			if err != nil {
+				stepErr = err
+				break
+			}
+			var value JSValue
+			value, stepErr = calleeObj.Invoke(vm, subject, args, CallFlags{})
+			if stepErr == nil {
+				interp.push(value)
+			}
+
+		case OpNew:
+			argc := instr.Arg
+			args := make([]JSValue, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = interp.pop()
+			}
+			consObj, err := vm.coerceToObject(interp.pop())
+			if err != nil {
+				stepErr = err
+				break
+			}
+			initObj := NewJSObject(&ProtoObject)
+			var value JSValue
+			value, stepErr = consObj.Invoke(vm, &initObj, args, CallFlags{isNew: true})
+			if stepErr == nil {
+				if _, isUnd := value.(JSUndefined); isUnd {
+					value = &initObj
+				}
+				interp.push(value)
+			}
+
+		case OpThrow:
+			stepErr = vm.makeException(interp.pop())
+
+		case OpTryEnter:
+			interp.handlers = append(interp.handlers, tryHandler{
+				catchPC:    instr.Arg,
+				stackDepth: len(interp.evalStack),
+			})
+
+		case OpTryLeave:
+			interp.handlers = interp.handlers[:len(interp.handlers)-1]
+
+		case OpJump:
+			frame.pc = instr.Arg
+
+		case OpJumpIfFalse:
+			if !vm.coerceToBoolean(interp.pop()) {
+				frame.pc = instr.Arg
+			}
+
+		case OpReturn:
+			return interp.pop(), nil, nil
+
+		case OpYield:
+			return interp.pop(), &frame, nil
+
+		default:
+			stepErr = fmt.Errorf("interpreter: unimplemented op: %v", instr.Op)
+		}
+
+		if stepErr != nil {
+			if !interp.recover(&frame, stepErr) {
+				return nil, nil, stepErr
+			}
+		}
+	}
+
+	return JSUndefined{}, nil, nil
+}
+
+// recover unwinds to the innermost try handler and resumes frame at its
+// catch pc, if the just-raised error is a thrown JS exception. Only a
+// ProgramException (always constructed and returned by value, never by
+// pointer, by ThrowError/makeException) can be caught this way — a
+// Go-level error (e.g. an unsupported opcode) always propagates out of
+// run, same as the tree-walker only catches *ast.ThrowStatement-raised
+// exceptions and lets other errors interrupt execution.
+func (interp *Interpreter) recover(frame *Frame, err error) bool {
+	if len(interp.handlers) == 0 {
+		return false
+	}
+	pexc, isExc := err.(ProgramException)
+	if !isExc {
+		return false
+	}
+
+	h := interp.handlers[len(interp.handlers)-1]
+	interp.handlers = interp.handlers[:len(interp.handlers)-1]
+	interp.evalStack = interp.evalStack[:h.stackDepth]
+	interp.push(pexc.bindCatchValue())
+	frame.pc = h.catchPC
+	return true
+}
+
+// getElem reads obj[key] for a computed member access, with the same
+// key-type handling as evalExpr's BracketExpression case.
+func getElem(vm *VM, obj *JSObject, key JSValue) (JSValue, error) {
+	switch key := key.(type) {
+	case JSNumber:
+		return obj.GetIndex(uint(key))
+	case JSBigInt:
+		return obj.GetIndex(uint(key.v.Int64()))
+	case JSString:
+		return obj.GetProperty(NameStr(string(key)), vm)
+	default:
+		return nil, vm.ThrowError("TypeError", fmt.Sprintf("invalid type for object key: %s", reflect.TypeOf(key).String()))
+	}
+}
+
+// setElem assigns obj[key] = value for a computed member access, with
+// the same key-type handling as doAssignment's BracketExpression case.
+func setElem(vm *VM, obj *JSObject, key JSValue, value JSValue) error {
+	switch key := key.(type) {
+	case JSString:
+		return obj.SetProperty(NameStr(string(key)), value, vm)
+	case JSNumber:
+		obj.SetIndex(int(key), value)
+		return nil
+	default:
+		return fmt.Errorf("object index/property is neither number nor string")
+	}
+}
+
+func (interp *Interpreter) constAt(frame Frame, idx int) JSValue {
+	_ = frame
+	return interp.script.Consts[idx]
+}
+
+func (interp *Interpreter) binaryOp(op token.Token, left, right JSValue) (JSValue, error) {
+	vm := interp.vm
+	switch op {
+	case token.STRICT_EQUAL:
+		return JSBoolean(vm.strictEqual(left, right)), nil
+	case token.STRICT_NOT_EQUAL:
+		return JSBoolean(!vm.strictEqual(left, right)), nil
+	case token.EQUAL:
+		ok, err := vm.looseEqual(left, right)
+		return JSBoolean(ok), err
+	case token.NOT_EQUAL:
+		ok, err := vm.looseEqual(left, right)
+		return JSBoolean(!ok), err
+	case token.PLUS:
+		return addition(vm, left, right)
+	case token.MINUS, token.MULTIPLY, token.SLASH:
+		return arithmeticOp(vm, left, right, op)
+	case token.LESS, token.LESS_OR_EQUAL, token.GREATER_OR_EQUAL, token.GREATER:
+		a, err := vm.coerceToPrimitive(left, PrimCoerceValueOfFirst)
+		if err != nil {
+			return nil, err
+		}
+		b, err := vm.coerceToPrimitive(right, PrimCoerceValueOfFirst)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		switch op {
+		case token.LESS:
+			ok, err = isLessThan(vm, a, b)
+		case token.LESS_OR_EQUAL:
+			ok, err = isNotLessThan(vm, b, a)
+		case token.GREATER_OR_EQUAL:
+			ok, err = isNotLessThan(vm, a, b)
+		case token.GREATER:
+			ok, err = isLessThan(vm, b, a)
+		}
+		return JSBoolean(ok), err
+	default:
+		return nil, fmt.Errorf("interpreter: unsupported binary operator: %s", op)
+	}
+}
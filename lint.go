@@ -0,0 +1,346 @@
+package modeledjs
+
+import (
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	parserFile "github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/token"
+)
+
+// Severity classifies how serious a Diagnostic is, in increasing order of
+// (un)importance — a Hint is purely informational, a Warning is worth a
+// second look, an Error means the rule is confident the code is wrong.
+// Unlike the syntax errors checker raises, no Severity here ever stops a
+// program from running: Lint is purely advisory.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityHint
+)
+
+func (sev Severity) String() string {
+	switch sev {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityHint:
+		return "hint"
+	default:
+		return "unknown"
+	}
+}
+
+// SuggestedFix is a machine-applicable fix for a Diagnostic: replacing
+// the source text spanning [From, To) with Replacement turns the
+// flagged code into what the rule considers correct. None of the rules
+// below populate one yet (computing a precise replacement needs the raw
+// source text, which this pass doesn't carry around), but embedders that
+// special-case it can already rely on Fix being nil when absent.
+type SuggestedFix struct {
+	From, To    parserFile.Idx
+	Replacement string
+}
+
+// Diagnostic is one finding produced by Lint: which rule raised it, how
+// serious it is, where in the source it applies, and an optional fix.
+type Diagnostic struct {
+	Rule     string
+	Severity Severity
+	Pos      parserFile.Position
+	Message  string
+	Fix      *SuggestedFix
+}
+
+// Lint statically analyzes src and returns every Diagnostic the
+// registered rules find, without executing any of it. A syntax error
+// that keeps src from parsing at all is reported as a single
+// SeverityError Diagnostic rather than returned as a Go error, so
+// callers always get a []Diagnostic back.
+func (vm *VM) Lint(src string) []Diagnostic {
+	program, err := ParseReader("<lint>", strings.NewReader(src))
+	if err != nil {
+		return []Diagnostic{{
+			Rule:     "syntax-error",
+			Severity: SeverityError,
+			Message:  err.Error(),
+		}}
+	}
+
+	pass := &lintPass{file: program.File, rules: defaultLintRules()}
+	ast.Walk(pass, program)
+	for _, rule := range pass.rules {
+		rule.finish(pass)
+	}
+	return pass.diags
+}
+
+// lintRule is one self-contained analyzer plugged into lintPass's single
+// AST walk. Rules that need state across the whole program (unused
+// variables) keep it on themselves between enter/exit/finish calls;
+// rules that only care about one node at a time (most of them) just
+// inspect node and call p.report.
+type lintRule interface {
+	enter(p *lintPass, node ast.Node)
+	exit(p *lintPass, node ast.Node)
+	// finish runs once after the whole program has been walked, for
+	// rules whose verdict depends on having seen everything first.
+	finish(p *lintPass)
+}
+
+// baseLintRule gives every embedder a no-op exit/finish, so a rule that
+// only needs enter (most of them) doesn't have to write empty methods.
+type baseLintRule struct{}
+
+func (baseLintRule) exit(p *lintPass, node ast.Node) {}
+func (baseLintRule) finish(p *lintPass)              {}
+
+// defaultLintRules is the rule set Lint runs. Order doesn't matter:
+// every rule only ever appends to lintPass.diags.
+func defaultLintRules() []lintRule {
+	return []lintRule{
+		&categoryMismatchRule{},
+		&unreachableCodeRule{},
+		&assignInConditionRule{},
+		&varRedeclarationRule{},
+		&withStatementRule{},
+		&unusedVariableRule{declared: map[string]parserFile.Idx{}, used: map[string]bool{}},
+	}
+}
+
+// lintPass drives defaultLintRules's rules over one ast.Walk, same
+// single-traversal shape as checker.
+type lintPass struct {
+	file  *parserFile.File
+	rules []lintRule
+	diags []Diagnostic
+}
+
+func (p *lintPass) Enter(node ast.Node) ast.Visitor {
+	for _, rule := range p.rules {
+		rule.enter(p, node)
+	}
+	return p
+}
+
+func (p *lintPass) Exit(node ast.Node) {
+	for _, rule := range p.rules {
+		rule.exit(p, node)
+	}
+}
+
+// report appends a Diagnostic positioned at node.Idx0().
+func (p *lintPass) report(rule string, sev Severity, node ast.Node, message string) {
+	var pos parserFile.Position
+	if p.file != nil {
+		if pp := p.file.Position(node.Idx0()); pp != nil {
+			pos = *pp
+		}
+	}
+	p.diags = append(p.diags, Diagnostic{Rule: rule, Severity: sev, Pos: pos, Message: message})
+}
+
+// categoryMismatchRule flags == / != / === / !== between two literals
+// whose Category() (reusing the same JSVCategory the runtime itself
+// switches on) can never be equal. For strict (in)equality this is
+// always sound: Type(x) !== Type(y) implies a false result, full stop.
+// For loose (in)equality it's only sound unless both sides' categories
+// are among Number/String/Boolean, since those three freely coerce into
+// each other (1 == "1" is true) — see looseEqCoercionPossible.
+type categoryMismatchRule struct{ baseLintRule }
+
+func (categoryMismatchRule) enter(p *lintPass, node ast.Node) {
+	be, ok := node.(*ast.BinaryExpression)
+	if !ok {
+		return
+	}
+
+	var strict bool
+	switch be.Operator {
+	case token.STRICT_EQUAL, token.STRICT_NOT_EQUAL:
+		strict = true
+	case token.EQUAL, token.NOT_EQUAL:
+		strict = false
+	default:
+		return
+	}
+
+	lv, lok := literalToJSValue(be.Left)
+	rv, rok := literalToJSValue(be.Right)
+	if !lok || !rok || lv.Category() == rv.Category() {
+		return
+	}
+	if !strict && looseEqCoercionPossible(lv.Category(), rv.Category()) {
+		return
+	}
+
+	result := "false"
+	if be.Operator == token.NOT_EQUAL || be.Operator == token.STRICT_NOT_EQUAL {
+		result = "true"
+	}
+	p.report("category-mismatch", SeverityWarning, node,
+		"comparison is always "+result+": operands have statically-different types")
+}
+
+// looseEqCoercionPossible reports whether ES's Abstract Equality
+// Comparison between two different-category primitives can still
+// succeed by coercion, making "always false" an unsound claim for ==/!=
+// even though the categories differ.
+func looseEqCoercionPossible(a, b JSVCategory) bool {
+	coercible := func(c JSVCategory) bool {
+		return c == VNumber || c == VString || c == VBoolean
+	}
+	return coercible(a) && coercible(b)
+}
+
+// unreachableCodeRule flags any statement following an unconditional
+// return/throw within the same statement list: control can never reach
+// it.
+type unreachableCodeRule struct{ baseLintRule }
+
+func (r unreachableCodeRule) enter(p *lintPass, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Program:
+		r.checkList(p, n.Body)
+	case *ast.BlockStatement:
+		r.checkList(p, n.List)
+	case *ast.CaseStatement:
+		r.checkList(p, n.Consequent)
+	}
+}
+
+func (unreachableCodeRule) checkList(p *lintPass, list []ast.Statement) {
+	terminated := false
+	for _, stmt := range list {
+		if terminated {
+			if _, isEmpty := stmt.(*ast.EmptyStatement); !isEmpty {
+				p.report("unreachable-code", SeverityWarning, stmt,
+					"unreachable code: this statement always follows a return/throw")
+			}
+			continue
+		}
+		switch stmt.(type) {
+		case *ast.ReturnStatement, *ast.ThrowStatement:
+			terminated = true
+		}
+	}
+}
+
+// assignInConditionRule flags a bare assignment used directly as an
+// if/while/do-while/for test, the classic `if (x = 1)` typo for `==`.
+type assignInConditionRule struct{ baseLintRule }
+
+func (assignInConditionRule) enter(p *lintPass, node ast.Node) {
+	var test ast.Expression
+	switch n := node.(type) {
+	case *ast.IfStatement:
+		test = n.Test
+	case *ast.WhileStatement:
+		test = n.Test
+	case *ast.DoWhileStatement:
+		test = n.Test
+	case *ast.ForStatement:
+		test = n.Test
+	default:
+		return
+	}
+
+	if assign, ok := test.(*ast.AssignExpression); ok && assign.Operator == token.ASSIGN {
+		p.report("assign-in-condition", SeverityWarning, assign,
+			"assignment used as a condition; did you mean == or ===?")
+	}
+}
+
+// varRedeclarationRule flags a `var` that redeclares a name already
+// bound earlier in the same function/program scope. It keeps its own
+// stack of per-scope name sets, pushed/popped around Program and
+// FunctionLiteral the same way checker tracks strict-mode context.
+type varRedeclarationRule struct {
+	baseLintRule
+	scopes []map[string]bool
+}
+
+func (r *varRedeclarationRule) enter(p *lintPass, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Program, *ast.FunctionLiteral:
+		_ = n
+		r.scopes = append(r.scopes, map[string]bool{})
+
+	case *ast.VariableExpression:
+		if len(r.scopes) == 0 {
+			return
+		}
+		scope := r.scopes[len(r.scopes)-1]
+		if scope[n.Name] {
+			p.report("var-redeclaration", SeverityHint, n,
+				"redeclaration of \""+n.Name+"\" in the same scope")
+		}
+		scope[n.Name] = true
+	}
+}
+
+func (r *varRedeclarationRule) exit(p *lintPass, node ast.Node) {
+	switch node.(type) {
+	case *ast.Program, *ast.FunctionLiteral:
+		r.scopes = r.scopes[:len(r.scopes)-1]
+	}
+}
+
+// withStatementRule flags every `with` statement. checker already turns
+// `with` in strict-mode code into a hard syntax error; this complements
+// it with an advisory for the (much more common) non-strict case, which
+// checker silently accepts today.
+type withStatementRule struct{ baseLintRule }
+
+func (withStatementRule) enter(p *lintPass, node ast.Node) {
+	if _, ok := node.(*ast.WithStatement); ok {
+		p.report("with-statement", SeverityWarning, node,
+			"use of `with` is deprecated and forbidden in strict mode; prefer explicit property access")
+	}
+}
+
+// unusedVariableRule flags a `var` binding whose name is never read
+// anywhere in the program. It's name-based rather than scope-based (a
+// name used anywhere counts as a use, even from an unrelated shadowing
+// scope), a deliberate simplification for a Hint-severity check: it can
+// miss a genuinely-unused binding shadowed by a same-named one in scope,
+// but it never flags a binding that actually is used.
+type unusedVariableRule struct {
+	baseLintRule
+	declared map[string]parserFile.Idx
+	used     map[string]bool
+}
+
+func (r *unusedVariableRule) enter(p *lintPass, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.VariableExpression:
+		if _, seen := r.declared[n.Name]; !seen {
+			r.declared[n.Name] = n.Idx
+		}
+	case *ast.Identifier:
+		r.used[n.Name] = true
+	}
+}
+
+func (r *unusedVariableRule) finish(p *lintPass) {
+	for name, idx := range r.declared {
+		if r.used[name] {
+			continue
+		}
+		var pos parserFile.Position
+		if p.file != nil {
+			if pp := p.file.Position(idx); pp != nil {
+				pos = *pp
+			}
+		}
+		p.diags = append(p.diags, Diagnostic{
+			Rule:     "unused-variable",
+			Severity: SeverityHint,
+			Pos:      pos,
+			Message:  "\"" + name + "\" is declared but never used",
+		})
+	}
+}
@@ -1,13 +1,15 @@
 package modeledjs
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"os"
 	"reflect"
-	"strconv"
 	"strings"
+	"unicode/utf16"
 
 	"github.com/robertkrimen/otto/ast"
 	parserFile "github.com/robertkrimen/otto/file"
@@ -30,6 +32,7 @@ const (
 	VObject
 	VBigInt
 	VFunction
+	VSymbol
 )
 
 type JSUndefined struct{}
@@ -52,9 +55,29 @@ type JSString string
 
 func (v JSString) Category() JSVCategory { return VString }
 
+// utf16Units returns v's UTF-16 code units, the unit ECMAScript itself
+// compares and indexes strings in — as opposed to v's raw Go bytes (UTF-8)
+// or runes (Unicode code points), either of which gives the wrong answer
+// for comparison ("é" < "f" needs the code unit 0x00E9, not the UTF-8 byte
+// 0xC3). Because v is backed by a Go string, a lone surrogate written into
+// source as e.g. "\uD800" can't be represented here: it isn't a valid
+// Unicode scalar value, so it already got replaced by U+FFFD on the way
+// into v. Supporting surrogates that round-trip unchanged would need v's
+// representation to change to []uint16 (or a WTF-8 variant), which is a
+// bigger change than this pass makes.
+func (v JSString) utf16Units() []uint16 {
+	return utf16.Encode([]rune(string(v)))
+}
+
 type Name struct {
 	string
 	isSymbol bool
+
+	// sym identifies which JSSymbol this Name was derived from. Two Names
+	// with isSymbol set compare equal (and thus collide as map keys) iff
+	// they share the same sym, matching how distinct Symbol() calls must
+	// never key the same property even when their descriptions match.
+	sym *JSSymbol
 }
 
 func (n Name) String() string {
@@ -69,10 +92,39 @@ func NameStr(s string) Name {
 	return Name{isSymbol: false, string: s}
 }
 
+// SymbolName wraps sym into the Name used to key descriptors for it,
+// alongside ordinary string-keyed properties.
+func SymbolName(sym *JSSymbol) Name {
+	return Name{string: sym.description, isSymbol: true, sym: sym}
+}
+
+// JSSymbol is the `symbol` primitive: a unique, non-string property key.
+// Equality is by identity (pointer), never by description — two Symbol()
+// calls with the same description are still distinct.
+type JSSymbol struct {
+	description string
+}
+
+func (v *JSSymbol) Category() JSVCategory { return VSymbol }
+
+func (v *JSSymbol) String() string {
+	return fmt.Sprintf("Symbol(%s)", v.description)
+}
+
 type JSObject struct {
 	Prototype   *JSObject
 	descriptors map[Name]*Descriptor
 
+	// keyOrder records own property keys in definition order (map
+	// iteration order in Go is randomized, but property enumeration
+	// order is observable in JS). Appended to on first definition,
+	// pruned on delete.
+	keyOrder []Name
+
+	// extensible is cleared by Object.seal/freeze: once false, no new own
+	// property can be added.
+	extensible bool
+
 	// at any given time, only one of these is supposed to be set
 	// replace all these with a single interface pointer and type assertions
 	arrayPart   []JSValue
@@ -81,6 +133,8 @@ type JSObject struct {
 	primNumber  JSNumber
 	primBoolean JSBoolean
 	primString  JSString
+	promisePart *PromiseState
+	regexpPart  *RegexpPart
 }
 
 type FunctionPart struct {
@@ -92,6 +146,24 @@ type FunctionPart struct {
 
 	file *parserFile.File
 	name string
+
+	// isGenerator and isAsync mark a function as needing a suspendable
+	// Frame (see generator.go) rather than a plain Invoke. The otto AST
+	// this package still parses with predates `function*`/`async`
+	// syntax, so the parser never sets these today; they're wired up
+	// now so makeFunction only needs to start populating them, not
+	// invent the plumbing, once a richer parser lands.
+	isGenerator bool
+	isAsync     bool
+
+	// compiledBody caches CompileProgram's result for body, so a hot
+	// function isn't re-lowered by Invoke on every call. compileFailed
+	// remembers that compiling body failed (the function uses a
+	// construct compiler.go doesn't cover yet), so Invoke doesn't retry
+	// compiling it — and rediscover the same error — on every call; it
+	// falls back to runStmt straight away instead.
+	compiledBody  *CompiledScript
+	compileFailed bool
 }
 type NativeCallback func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error)
 type CallFlags struct {
@@ -127,6 +199,7 @@ func NewJSObject(proto *JSObject) JSObject {
 	return JSObject{
 		Prototype:   proto,
 		descriptors: make(map[Name]*Descriptor),
+		extensible:  true,
 	}
 }
 
@@ -145,6 +218,12 @@ func (jso *JSObject) getOwnPropertyDescriptor(name Name) (*Descriptor, bool) {
 	d, ok := jso.descriptors[name]
 	return d, ok
 }
+
+// OwnPropertyDescriptor exposes getOwnPropertyDescriptor to callers outside
+// the package, for Object.getOwnPropertyDescriptor and Reflect.getOwnPropertyDescriptor.
+func (jso *JSObject) OwnPropertyDescriptor(name Name) (*Descriptor, bool) {
+	return jso.getOwnPropertyDescriptor(name)
+}
 func (jso *JSObject) GetOwnProperty(name Name, vm *VM) (JSValue, error) {
 	descriptor, isThere := jso.descriptors[name]
 	if !isThere {
@@ -170,6 +249,16 @@ func (jso *JSObject) GetProperty(name Name, vm *VM) (JSValue, error) {
 	}
 
 }
+
+// SetProperty implements [[Set]]: it walks the prototype chain looking for
+// an existing own or inherited descriptor, invokes a setter if the
+// descriptor is an accessor, and otherwise writes (or creates) a plain data
+// property on jso. A non-writable data property, or an accessor with no
+// setter, makes the assignment a no-op in sloppy mode; in strict mode
+// (determined from vm.curScope) it throws a TypeError instead, per the
+// usual JS assignment semantics. vm may be nil (e.g. from SetIndex), in
+// which case the assignment is always treated as sloppy since there's no
+// scope to consult.
 func (jso *JSObject) SetProperty(name Name, value JSValue, vm *VM) error {
 	var descriptor *Descriptor
 	isThere := false
@@ -181,48 +270,279 @@ func (jso *JSObject) SetProperty(name Name, value JSValue, vm *VM) error {
 		}
 	}
 
-	// TODO Honor writable, configurable, etc.
 	if !isThere {
 		if value == nil {
 			panic("value can't be nil here")
 		}
+		if !jso.extensible {
+			return jso.rejectAssignment(name, vm)
+		}
 
-		jso.descriptors[name] = &Descriptor{
+		jso.defineOwnProperty(name, &Descriptor{
 			value:        value,
-			configurable: false,
-			enumerable:   false,
-			writable:     false,
-		}
+			configurable: true,
+			enumerable:   true,
+			writable:     true,
+		})
 		return nil
-	} else if descriptor.set != nil {
+	}
+
+	if descriptor.get != nil || descriptor.set != nil {
+		if descriptor.set == nil {
+			return jso.rejectAssignment(name, vm)
+		}
 		_, err := descriptor.set.Invoke(vm, jso, []JSValue{value}, CallFlags{})
 		// descriptor used but remains unchanged
 		return err
-	} else {
-		descriptor.value = value
-		return nil
 	}
+
+	if !descriptor.writable {
+		return jso.rejectAssignment(name, vm)
+	}
+
+	descriptor.value = value
+	return nil
+}
+
+// rejectAssignment is what SetProperty returns for an assignment [[Set]]
+// refuses: per spec this throws in strict mode and is a silent no-op
+// otherwise.
+func (jso *JSObject) rejectAssignment(name Name, vm *VM) error {
+	if vm != nil && isStrict(vm.curScope) {
+		return vm.ThrowError("TypeError", fmt.Sprintf("Cannot assign to read only property '%s' of object", name.String()))
+	}
+	return nil
 }
+
 func (jso *JSObject) getOrDefineProperty(name Name) (ds *Descriptor) {
 	ds, isThere := jso.getOwnPropertyDescriptor(name)
 	if !isThere {
-		ds = jso.DefineProperty(name, Descriptor{value: JSUndefined{}})
+		ds, _ = jso.DefineProperty(name, Descriptor{value: JSUndefined{}, configurable: true, enumerable: true, writable: true})
 	}
 	return
 }
 
-func (jso *JSObject) DefineProperty(name Name, descriptor Descriptor) *Descriptor {
-	descriptor.writable = true
-	descriptor.configurable = true
-	descriptor.enumerable = true
+// DefineProperty implements [[DefineOwnProperty]]: descriptor replaces
+// whatever own descriptor (if any) is stored under name, after checking
+// that the replacement is legal. A property that isn't configurable can't
+// be made configurable, change its enumerability, or switch between data
+// and accessor; an accessor keeps its getter/setter, and a data property
+// can only go from writable to non-writable. A non-writable one's value is
+// fixed, while a writable one's value may still change.
+func (jso *JSObject) DefineProperty(name Name, descriptor Descriptor) (*Descriptor, error) {
+	existing, isThere := jso.getOwnPropertyDescriptor(name)
+	if isThere {
+		if err := validatePropertyRedefinition(existing, descriptor); err != nil {
+			return nil, err
+		}
+	} else if !jso.extensible {
+		return nil, fmt.Errorf("object is not extensible")
+	}
+
 	dp := &descriptor
-	jso.descriptors[name] = dp
-	return dp
+	jso.defineOwnProperty(name, dp)
+	return dp, nil
+}
+
+// defineOwnProperty stores descriptor under name, recording name in
+// keyOrder the first time it's defined so enumeration order stays
+// deterministic.
+func (jso *JSObject) defineOwnProperty(name Name, descriptor *Descriptor) {
+	if _, isThere := jso.descriptors[name]; !isThere {
+		jso.keyOrder = append(jso.keyOrder, name)
+	}
+	jso.descriptors[name] = descriptor
+}
+
+func isAccessorDescriptor(d *Descriptor) bool {
+	return d.get != nil || d.set != nil
+}
+
+// sameValueDescriptorData reports whether a and b are the same value for
+// the purposes of the "value didn't change" exception to the
+// non-configurable/non-writable redefinition rule. JSValue's concrete
+// types are all comparable with ==, except JSBigInt (compared via Cmp,
+// since it's backed by a *big.Int pointer), so this just needs to line
+// the dynamic types up first.
+func sameValueDescriptorData(a, b JSValue) bool {
+	switch av := a.(type) {
+	case JSNumber:
+		bv, ok := b.(JSNumber)
+		return ok && av == bv
+	case JSString:
+		bv, ok := b.(JSString)
+		return ok && av == bv
+	case JSBoolean:
+		bv, ok := b.(JSBoolean)
+		return ok && av == bv
+	case JSBigInt:
+		bv, ok := b.(JSBigInt)
+		return ok && av.v.Cmp(bv.v) == 0
+	case *JSObject:
+		bv, ok := b.(*JSObject)
+		return ok && av == bv
+	case *JSSymbol:
+		bv, ok := b.(*JSSymbol)
+		return ok && av == bv
+	case JSUndefined:
+		_, ok := b.(JSUndefined)
+		return ok
+	case JSNull:
+		_, ok := b.(JSNull)
+		return ok
+	default:
+		return false
+	}
+}
+
+func validatePropertyRedefinition(existing *Descriptor, next Descriptor) error {
+	if existing.configurable {
+		return nil
+	}
+	if next.configurable {
+		return fmt.Errorf("cannot redefine non-configurable property as configurable")
+	}
+	if next.enumerable != existing.enumerable {
+		return fmt.Errorf("cannot change enumerable attribute of non-configurable property")
+	}
+	if isAccessorDescriptor(existing) != isAccessorDescriptor(&next) {
+		return fmt.Errorf("cannot redefine property between data and accessor descriptor")
+	}
+	if isAccessorDescriptor(existing) {
+		if existing.get != next.get || existing.set != next.set {
+			return fmt.Errorf("cannot redefine non-configurable accessor property")
+		}
+		return nil
+	}
+	if !existing.writable {
+		if next.writable {
+			return fmt.Errorf("cannot redefine non-writable property as writable")
+		}
+		if !sameValueDescriptorData(existing.value, next.value) {
+			return fmt.Errorf("cannot change value of non-writable, non-configurable property")
+		}
+	}
+	return nil
 }
+
+// DeleteProperty implements [[Delete]]: deleting an absent property
+// trivially succeeds, deleting a non-configurable one fails, and anything
+// else is removed (including from keyOrder, so later enumeration doesn't
+// see a dangling name).
 func (jso *JSObject) DeleteProperty(name Name) bool {
-	_, wasThere := jso.descriptors[name]
+	descriptor, wasThere := jso.descriptors[name]
+	if !wasThere {
+		return true
+	}
+	if !descriptor.configurable {
+		return false
+	}
+
 	delete(jso.descriptors, name)
-	return wasThere
+	for i, n := range jso.keyOrder {
+		if n == name {
+			jso.keyOrder = append(jso.keyOrder[:i], jso.keyOrder[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// OwnPropertyNames returns jso's own string-keyed property names (both
+// enumerable and non-enumerable) in definition order, per
+// Object.getOwnPropertyNames.
+func (jso *JSObject) OwnPropertyNames() []Name {
+	var names []Name
+	for _, name := range jso.keyOrder {
+		if !name.isSymbol {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// OwnPropertySymbols returns jso's own symbol-keyed property names in
+// definition order, per Object.getOwnPropertySymbols.
+func (jso *JSObject) OwnPropertySymbols() []Name {
+	var names []Name
+	for _, name := range jso.keyOrder {
+		if name.isSymbol {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// OwnEnumerableStringKeys returns jso's own enumerable string-keyed
+// property names in definition order: the set for...in and object spread
+// iterate over (symbols and non-enumerable properties are skipped).
+func (jso *JSObject) OwnEnumerableStringKeys() []Name {
+	var names []Name
+	for _, name := range jso.keyOrder {
+		if name.isSymbol {
+			continue
+		}
+		if d, ok := jso.descriptors[name]; ok && d.enumerable {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// preventExtensions stops jso from gaining any new own property; existing
+// ones are untouched. Used by seal and freeze, which both imply it.
+func (jso *JSObject) preventExtensions() {
+	jso.extensible = false
+}
+
+// seal marks every own property non-configurable and prevents further
+// extension, per Object.seal.
+func (jso *JSObject) seal() {
+	jso.preventExtensions()
+	for _, d := range jso.descriptors {
+		d.configurable = false
+	}
+}
+
+// freeze marks every own data property non-configurable and non-writable
+// (accessor properties are left writable-less, since that attribute
+// doesn't apply to them) and prevents further extension, per Object.freeze.
+func (jso *JSObject) freeze() {
+	jso.preventExtensions()
+	for _, d := range jso.descriptors {
+		d.configurable = false
+		if !isAccessorDescriptor(d) {
+			d.writable = false
+		}
+	}
+}
+
+// isSealed reports whether jso is non-extensible and every own property is
+// non-configurable, per Object.isSealed.
+func (jso *JSObject) isSealed() bool {
+	if jso.extensible {
+		return false
+	}
+	for _, d := range jso.descriptors {
+		if d.configurable {
+			return false
+		}
+	}
+	return true
+}
+
+// isFrozen reports whether jso is sealed and, additionally, every own data
+// property is non-writable, per Object.isFrozen.
+func (jso *JSObject) isFrozen() bool {
+	if !jso.isSealed() {
+		return false
+	}
+	for _, d := range jso.descriptors {
+		if !isAccessorDescriptor(d) && d.writable {
+			return false
+		}
+	}
+	return true
 }
 func (jso *JSObject) GetIndex(ndx uint) (JSValue, error) {
 	if jso.arrayPart != nil {
@@ -249,6 +569,7 @@ func NewNativeFunction(paramNames []string, cb NativeCallback) JSObject {
 	return JSObject{
 		Prototype:   &ProtoFunction,
 		descriptors: make(map[Name]*Descriptor),
+		extensible:  true,
 		funcPart: &FunctionPart{
 			isStrict: true,
 			native:   cb,
@@ -266,6 +587,12 @@ func (callee *JSObject) Invoke(vm *VM, this JSValue, args []JSValue, flags CallF
 		return JSUndefined{}, err
 	}
 
+	vm.callDepth++
+	defer func() { vm.callDepth-- }()
+	if vm.maxCallDepth > 0 && vm.callDepth > vm.maxCallDepth {
+		return nil, vm.ThrowError("RangeError", "Maximum call stack size exceeded")
+	}
+
 	if !flags.isNew && !fp.isStrict {
 		// do this-substitution
 		_, isUnd := this.(JSUndefined)
@@ -279,13 +606,16 @@ func (callee *JSObject) Invoke(vm *VM, this JSValue, args []JSValue, flags CallF
 		}
 	}
 
+	caller := currentCall(vm.curScope)
+	callSite := vm.synCtx.currentPosition()
+
 	saveScope := vm.curScope
 	vm.curScope = fp.lexicalScope
 	defer func() { vm.curScope = saveScope }()
 
 	ret = JSUndefined{}
 	vm.withScope(func() {
-		vm.curScope.call = &ScopeCall{this: this}
+		vm.curScope.call = &ScopeCall{this: this, funcPart: fp, caller: caller, callSite: callSite}
 		vm.curScope.isSetStrict = fp.isStrict
 
 		// the function's name is not overridable within the function itself
@@ -321,14 +651,30 @@ func (callee *JSObject) Invoke(vm *VM, this JSValue, args []JSValue, flags CallF
 				ret, err = fp.native(vm, this, args, CallFlags{})
 			} else if fp.body != nil {
 				check := vm.curScope
-				err = vm.runStmt(fp.body)
-				if check != vm.curScope {
-					panic("scope stack manipulated!")
+
+				vm.hoistVars([]ast.Statement{fp.body})
+
+				if !vm.bytecodeDisabled && fp.compiledBody == nil && !fp.compileFailed {
+					fp.compiledBody, err = compileFunctionBody(fp)
+					if err != nil {
+						fp.compileFailed = true
+						fp.compiledBody = nil
+						err = nil
+					}
 				}
 
-				if retWrapper, isReturn := err.(ReturnValue); isReturn {
-					ret = retWrapper.JSValue
-					err = nil
+				if fp.compiledBody != nil {
+					ret, err = vm.RunCompiled(fp.compiledBody)
+				} else {
+					err = vm.runStmt(fp.body)
+					if retWrapper, isReturn := err.(ReturnValue); isReturn {
+						ret = retWrapper.JSValue
+						err = nil
+					}
+				}
+
+				if check != vm.curScope {
+					panic("scope stack manipulated!")
 				}
 
 			} else {
@@ -346,10 +692,33 @@ func NewJSArray() (obj *JSObject) {
 	return
 }
 
-type JSBigInt int64
+// JSBigInt is a BigInt value: unlike JSNumber, it must not silently lose
+// precision, so it's backed by math/big.Int rather than any fixed-width
+// Go integer. The zero JSBigInt{} has a nil v and is not a valid value;
+// bigIntFromInt64 and NewBigInt are the ways to get one.
+type JSBigInt struct {
+	v *big.Int
+}
+
+// NewBigInt wraps v as a JSBigInt. v is not copied, so callers must not
+// mutate it afterwards.
+func NewBigInt(v *big.Int) JSBigInt { return JSBigInt{v: v} }
+
+func bigIntFromInt64(i int64) JSBigInt { return JSBigInt{v: big.NewInt(i)} }
 
 func (v JSBigInt) Category() JSVCategory { return VBigInt }
 
+func (v JSBigInt) String() string { return v.v.String() }
+
+// float64 renders v the same way coerceToNumber does: via big.Float, so
+// values outside float64's exact-integer range round the way the spec's
+// BigInt-to-Number conversion requires instead of overflowing int64.
+func (v JSBigInt) float64() float64 {
+	f := new(big.Float).SetInt(v.v)
+	f64, _ := f.Float64()
+	return f64
+}
+
 var (
 	ProtoObject   = NewJSObject(nil)
 	ProtoFunction = NewJSObject(&ProtoObject)
@@ -500,6 +869,66 @@ func (rv ReturnValue) Error() string {
 type ProgramException struct {
 	exceptionValue JSValue
 	context        ProgramContext
+
+	// frames is the call stack captured by (*VM).captureFrames at the
+	// moment the exception was created, innermost call first.
+	frames []StackFrame
+}
+
+// StackFrame is one activation on the call stack captured when a
+// ProgramException is thrown: which function was running, where it was
+// defined, and the source position execution had reached within it.
+type StackFrame struct {
+	FuncName string
+	File     *parserFile.File
+	Pos      parserFile.Position
+}
+
+// Frames returns the call stack captured when the exception was thrown,
+// innermost call first, for embedders that want more structure than the
+// "stack" string stamped onto caught exception objects (see
+// (ProgramException).bindCatchValue).
+func (pexc ProgramException) Frames() []StackFrame {
+	return pexc.frames
+}
+
+// formatStack renders pexc's message and frames V8-style, e.g.:
+//
+//	Error: boom
+//	    at inner (test.js:3:9)
+//	    at outer (test.js:7:3)
+func (pexc ProgramException) formatStack() string {
+	lines := make([]string, 1, 1+len(pexc.frames))
+	lines[0] = fmt.Sprintf("Error: %s", pexc.message())
+	for _, f := range pexc.frames {
+		name := f.FuncName
+		if name == "" {
+			name = "<anonymous>"
+		}
+		filename := "<unknown>"
+		if f.File != nil {
+			filename = f.File.Name()
+		}
+		lines = append(lines, fmt.Sprintf("    at %s (%s:%d:%d)", name, filename, f.Pos.Line, f.Pos.Column))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// bindCatchValue is what a `catch (e)` clause should bind e to: the
+// thrown value, stamped with a non-enumerable "stack" property first if
+// it's an object (a plain string/number throw has nowhere to put one).
+// Used by both the tree-walker's TryStatement and the bytecode
+// interpreter's recover, so the two execution paths agree on what JS code
+// observes.
+func (pexc ProgramException) bindCatchValue() JSValue {
+	if excObj, isObj := pexc.exceptionValue.(*JSObject); isObj {
+		excObj.defineOwnProperty(NameStr("stack"), &Descriptor{
+			value:        JSString(pexc.formatStack()),
+			configurable: true,
+			writable:     true,
+		})
+	}
+	return pexc.exceptionValue
 }
 
 func (pexc ProgramException) message() string {
@@ -600,6 +1029,34 @@ func (pctx *ProgramContext) Pop(nodeCheck ast.Node) {
 	pctx.stack = pctx.stack[:sl-1]
 }
 
+// currentPosition returns the source position of whatever node is
+// innermost on the stack, i.e. where execution currently is. The bytecode
+// interpreter doesn't push per-instruction nodes the way runStmt/evalExpr
+// do (see RunCompiled), so this can be stale or zero-valued while running
+// compiled code; it's best-effort, used only to seed stack traces.
+func (pctx *ProgramContext) currentPosition() parserFile.Position {
+	if len(pctx.stack) == 0 {
+		return parserFile.Position{}
+	}
+	return pctx.stack[len(pctx.stack)-1].start
+}
+
+// currentRange returns the file name and [start, end) source range of
+// whatever node is innermost on the stack, for Coverage.record. ok is
+// false if the stack is empty or the top item has no file (shouldn't
+// happen once PushFile has run, but matches Push's own nil-file
+// tolerance).
+func (pctx *ProgramContext) currentRange() (file string, start, end parserFile.Position, ok bool) {
+	if len(pctx.stack) == 0 {
+		return "", parserFile.Position{}, parserFile.Position{}, false
+	}
+	top := pctx.stack[len(pctx.stack)-1]
+	if top.file == nil {
+		return "", parserFile.Position{}, parserFile.Position{}, false
+	}
+	return top.file.Name(), top.start, top.end, true
+}
+
 type DeclKind uint8
 
 const (
@@ -632,6 +1089,23 @@ type Scope struct {
 
 type ScopeCall struct {
 	this JSValue
+
+	// funcPart is the function whose invocation opened this call, so a
+	// stack trace (see (*VM).captureFrames) can report its name and
+	// defining file. Always set by Invoke; nil for the ScopeCall module.go
+	// gives a module's top-level scope.
+	funcPart *FunctionPart
+
+	// caller is the call-scope of the dynamic caller, i.e. the scope
+	// Invoke was entered from — not scope.parent, which is the callee's
+	// *lexical* scope and for a closure may have nothing to do with who
+	// actually called it. nil for the outermost call.
+	caller *Scope
+
+	// callSite is where, in the caller, this call was made — the
+	// position a stack trace reports for the caller's frame once
+	// execution is inside this call.
+	callSite parserFile.Position
 }
 
 func isStrict(s *Scope) (ret bool) {
@@ -746,19 +1220,159 @@ type VM struct {
 	globalObject JSObject
 	curScope     *Scope
 	synCtx       ProgramContext
+
+	// microtasks holds pending promise reactions, drained by
+	// RunMicrotasks. See promise.go.
+	microtasks []func()
+
+	// asyncTracker, if set via SetAsyncContextTracker, is consulted around
+	// every generator/async resumption. See AsyncContextTracker.
+	asyncTracker AsyncContextTracker
+
+	// compileCache memoizes CompiledScripts produced via PrecompileScript
+	// and RunCompiledSource, keyed by source text, strictness, and scope
+	// shape. See compilecache.go.
+	compileCache *CompileCache
+
+	// symbolRegistry backs Symbol.for/Symbol.keyFor: the global symbol
+	// registry is per-realm, so it lives on the VM rather than as a
+	// package-level map.
+	symbolRegistry map[string]*JSSymbol
+
+	// wellKnownSymbols holds the engine's own singleton Symbol values
+	// (Symbol.iterator and friends), created alongside the global Symbol
+	// constructor in createGlobalObject. They're per-realm like
+	// symbolRegistry, but unlike Symbol.for() keys they're never looked
+	// up by description: host code (coerceToPrimitive, for-of, ...)
+	// references the struct fields directly.
+	wellKnownSymbols wellKnownSymbols
+
+	// loader resolves and reads the source behind every specifier
+	// vm.Import/require() sees. nil until SetModuleLoader is called or
+	// VMOptions.ModuleBaseDir installs an FSLoader. See module.go.
+	loader ModuleLoader
+
+	// regexpEngine compiles RegExp literals and `new RegExp(...)`
+	// patterns. nil until SetRegexpEngine is called, in which case
+	// newRegExp falls back to goRegexpEngine. See regexp.go.
+	regexpEngine RegexpEngine
+
+	// modules caches one ModuleRecord per resolved specifier, so two
+	// importers of the same file share its bindings and it evaluates
+	// only once.
+	modules map[string]*ModuleRecord
+
+	// moduleGlobalScope is the shared parent scope every module's own
+	// top-level scope chains to, lazily created by moduleBaseScope.
+	moduleGlobalScope *Scope
+
+	// bytecodeDisabled forces Invoke to always run a function body
+	// through runStmt, skipping the compile-and-cache fast path. Off by
+	// default; set via SetInterpreter("tree"), e.g. for an apples-to-
+	// apples comparison against the bytecode path in a benchmark.
+	bytecodeDisabled bool
+
+	// constantFoldingEnabled mirrors VMOptions.EnableConstantFolding: set
+	// at construction time, consulted by optimizeProgram every time this
+	// VM parses a program. See optimizer.go.
+	constantFoldingEnabled bool
+
+	// maxCallDepth mirrors VMOptions.MaxCallDepth; callDepth is how many
+	// Invoke activations are currently nested on Go's own call stack.
+	// Every Invoke (JS function calls, getters/setters, and every
+	// native→JS callback: replacer functions, promise handlers, ...)
+	// recurses through Go rather than through a flattened interpreter
+	// call stack, so without this a runaway recursive JS program crashes
+	// the process instead of raising a catchable RangeError.
+	//
+	// This only bounds the crash; it doesn't make native→JS reentry not
+	// recurse through Go's stack. That would need Invoke rebuilt around
+	// an explicit evalStack/callStack and a VM.Step, with a VM.CallJS
+	// entry point for native callers, so a deeply-recursive JS program
+	// degrades to a catchable RangeError at a depth Go's own stack can
+	// always accommodate rather than one tuned to it. That's a
+	// standalone rewrite of the call path (compiler.go's OpCall/OpReturn
+	// and every native callback site), out of scope for this commit.
+	maxCallDepth int
+	callDepth    int
+
+	// coverage, if set via EnableCoverage, receives one hit per executed
+	// statement/expression (tree-walker) or Instruction (bytecode), so a
+	// driver like cmd/run262 can report which source ranges the
+	// currently-passing test262 subset actually exercises. nil by
+	// default, so an unconfigured VM pays no recording overhead.
+	coverage *Coverage
+}
+
+// EnableCoverage attaches cov to vm: every statement/expression the
+// tree-walker runs, and every Instruction the bytecode Interpreter
+// executes, records a hit against it. cov may be shared across several
+// VMs (see Coverage.record's own locking) so a worker pool like
+// runMany's can merge per-goroutine execution into one profile.
+func (vm *VM) EnableCoverage(cov *Coverage) {
+	vm.coverage = cov
+}
+
+// recordCoverage records the innermost node on synCtx's stack as an
+// execution hit, called from runStmt/evalExpr right after they push
+// themselves. A no-op if coverage isn't enabled.
+func (vm *VM) recordCoverage() {
+	if vm.coverage == nil {
+		return
+	}
+	if file, start, end, ok := vm.synCtx.currentRange(); ok {
+		vm.coverage.record(file, start, end)
+	}
+}
+
+// SetInterpreter selects which evaluator runs JS function bodies and
+// top-level scripts: "bytecode" (the default) compiles with compiler.go
+// and runs the result on the Interpreter, falling back to the tree-walker
+// for any construct the compiler doesn't cover yet; "tree" always uses
+// the tree-walker. RunScriptReader et al. go through the same fallback
+// as the per-call fast path below, so a program that fails to compile
+// still runs correctly, just without the bytecode path's speedup.
+func (vm *VM) SetInterpreter(mode string) error {
+	switch mode {
+	case "bytecode", "":
+		vm.bytecodeDisabled = false
+	case "tree":
+		vm.bytecodeDisabled = true
+	default:
+		return fmt.Errorf("modeledjs: unknown -interp mode: %q (want %q or %q)", mode, "tree", "bytecode")
+	}
+	return nil
 }
 
 func NewVM() (vm VM) {
-	vm.globalObject = createGlobalObject()
-	return
+	return NewVMWithOptions(VMOptions{})
 }
 
-func createGlobalObject() (G JSObject) {
+// wellKnownSymbols holds the handful of Symbol values the engine itself
+// keys behavior off, as opposed to ones only user code cares about. They're
+// exposed to JS as properties of the Symbol constructor (Symbol.iterator,
+// ...) but also kept here so host code can look up the same singleton
+// without going through a global property read.
+type wellKnownSymbols struct {
+	toPrimitive   *JSSymbol
+	iterator      *JSSymbol
+	asyncIterator *JSSymbol
+	hasInstance   *JSSymbol
+}
+
+func createGlobalObject() (G JSObject, wellKnown wellKnownSymbols) {
 	G = NewJSObject(&ProtoObject)
 
 	consString := addPrimitiveWrapperConstructor(
 		&G, "String", &ProtoString,
 		func(vm *VM, jsv JSValue) (JSString, error) {
+			// Unlike implicit ToString (coerceToString), the String()
+			// function/constructor never falls back to a symbol's
+			// description: it throws, same as Number()/BigInt() do for
+			// symbols.
+			if _, isSym := jsv.(*JSSymbol); isSym {
+				return "", vm.ThrowError("TypeError", "cannot convert a Symbol value to a string")
+			}
 			return vm.coerceToString(jsv)
 		},
 		func(obj *JSObject, jss JSString) {
@@ -806,6 +1420,84 @@ func createGlobalObject() (G JSObject) {
 		})
 	G.SetProperty(NameStr("BigInt"), &consBigInt, nil)
 
+	// Symbol is slightly different too (not a constructor)
+	consSymbol := NewNativeFunction(
+		[]string{"description"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			if flags.isNew {
+				return nil, vm.ThrowError("TypeError", "Symbol is not a constructor")
+			}
+
+			description := ""
+			if len(args) > 0 {
+				if _, isUndef := args[0].(JSUndefined); !isUndef {
+					descStr, err := vm.coerceToString(args[0])
+					if err != nil {
+						return nil, err
+					}
+					description = string(descStr)
+				}
+			}
+			return &JSSymbol{description: description}, nil
+		})
+
+	symbolFor := NewNativeFunction(
+		[]string{"key"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			var arg JSValue = JSUndefined{}
+			if len(args) > 0 {
+				arg = args[0]
+			}
+			key, err := vm.coerceToString(arg)
+			if err != nil {
+				return nil, err
+			}
+			if sym, isThere := vm.symbolRegistry[string(key)]; isThere {
+				return sym, nil
+			}
+			sym := &JSSymbol{description: string(key)}
+			vm.symbolRegistry[string(key)] = sym
+			return sym, nil
+		})
+	consSymbol.SetProperty(NameStr("for"), &symbolFor, nil)
+
+	symbolKeyFor := NewNativeFunction(
+		[]string{"sym"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			var arg JSValue = JSUndefined{}
+			if len(args) > 0 {
+				arg = args[0]
+			}
+			sym, isSym := arg.(*JSSymbol)
+			if !isSym {
+				return nil, vm.ThrowError("TypeError", "Symbol.keyFor: argument is not a symbol")
+			}
+			for key, registered := range vm.symbolRegistry {
+				if registered == sym {
+					return JSString(key), nil
+				}
+			}
+			return JSUndefined{}, nil
+		})
+	consSymbol.SetProperty(NameStr("keyFor"), &symbolKeyFor, nil)
+
+	// Well-known symbols: shared singletons the engine consults directly
+	// (coerceToPrimitive, future for-of/instanceof support, ...), exposed
+	// to JS the same way V8/SpiderMonkey do, as non-registry properties
+	// of the Symbol constructor.
+	wellKnown = wellKnownSymbols{
+		toPrimitive:   &JSSymbol{description: "Symbol.toPrimitive"},
+		iterator:      &JSSymbol{description: "Symbol.iterator"},
+		asyncIterator: &JSSymbol{description: "Symbol.asyncIterator"},
+		hasInstance:   &JSSymbol{description: "Symbol.hasInstance"},
+	}
+	consSymbol.SetProperty(NameStr("toPrimitive"), wellKnown.toPrimitive, nil)
+	consSymbol.SetProperty(NameStr("iterator"), wellKnown.iterator, nil)
+	consSymbol.SetProperty(NameStr("asyncIterator"), wellKnown.asyncIterator, nil)
+	consSymbol.SetProperty(NameStr("hasInstance"), wellKnown.hasInstance, nil)
+
+	G.SetProperty(NameStr("Symbol"), &consSymbol, nil)
+
 	consObject := NewNativeFunction(
 		[]string{"value"},
 		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
@@ -824,6 +1516,8 @@ func createGlobalObject() (G JSObject) {
 				constructor = consNumber
 			case JSString:
 				constructor = consString
+			case *JSSymbol:
+				constructor = &consSymbol
 			case *JSObject:
 				return spec, nil
 			case JSUndefined, JSNull:
@@ -838,6 +1532,20 @@ func createGlobalObject() (G JSObject) {
 		},
 	)
 	G.SetProperty(NameStr("Object"), &consObject, nil)
+	installObjectStatics(&consObject)
+	G.SetProperty(NameStr("Reflect"), makeReflectObject(), nil)
+
+	globalRequire := NewNativeFunction([]string{"specifier"}, func(vm *VM, _ JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		if len(args) == 0 {
+			return nil, vm.ThrowError("TypeError", "require: missing specifier argument")
+		}
+		specifier, err := vm.coerceToString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return vm.requireFromCurrentFile(string(specifier))
+	})
+	G.SetProperty(NameStr("require"), &globalRequire, nil)
 
 	consArray := NewNativeFunction(
 		nil,
@@ -849,6 +1557,44 @@ func createGlobalObject() (G JSObject) {
 	)
 	G.SetProperty(NameStr("Array"), &consArray, nil)
 
+	consRegExp := NewNativeFunction(
+		[]string{"pattern", "flags"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			var patternArg JSValue = JSUndefined{}
+			if len(args) > 0 {
+				patternArg = args[0]
+			}
+
+			// new RegExp(existingRegExp) copies its source/flags, same as
+			// the regexpArg coercion match/search/replace/split do for a
+			// non-RegExp argument.
+			var pattern, flagsStr string
+			if existing, isObj := patternArg.(*JSObject); isObj && existing.regexpPart != nil {
+				pattern = existing.regexpPart.pattern
+				flagsStr = existing.regexpPart.flags.String()
+			} else if _, isUndef := patternArg.(JSUndefined); !isUndef {
+				patternStr, err := vm.coerceToString(patternArg)
+				if err != nil {
+					return nil, err
+				}
+				pattern = string(patternStr)
+			}
+
+			if len(args) > 1 {
+				if _, isUndef := args[1].(JSUndefined); !isUndef {
+					flagsArg, err := vm.coerceToString(args[1])
+					if err != nil {
+						return nil, err
+					}
+					flagsStr = string(flagsArg)
+				}
+			}
+
+			return vm.newRegExp(pattern, flagsStr)
+		},
+	)
+	G.SetProperty(NameStr("RegExp"), &consRegExp, nil)
+
 	cashPrint := NewNativeFunction(
 		[]string{"value"},
 		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
@@ -889,9 +1635,7 @@ func addPrimitiveWrapperConstructor[T JSValue](
 
 			if flags.isNew {
 				// discard subject, wrap into NEW object
-				subjObj := NewJSObject(prototype)
-				primInit(&subjObj, prim)
-				return &subjObj, nil
+				return boxPrimitive(prototype, prim, primInit), nil
 			}
 
 			return prim, nil
@@ -901,6 +1645,18 @@ func addPrimitiveWrapperConstructor[T JSValue](
 	return &constructor
 }
 
+// boxPrimitive builds a wrapper *JSObject around prim under prototype,
+// storing it via primInit (e.g. "(obj *JSObject, n JSNumber) { obj.primNumber = n }").
+// It's the one place that actually allocates a primitive wrapper object,
+// shared by addPrimitiveWrapperConstructor's `new Number(...)`-style path
+// and coerceToObject's BigInt branch, which needs the same box but can't
+// reach it via isNew since BigInt itself isn't a constructor.
+func boxPrimitive[T JSValue](prototype *JSObject, prim T, primInit func(obj *JSObject, prim T)) *JSObject {
+	obj := NewJSObject(prototype)
+	primInit(&obj, prim)
+	return &obj
+}
+
 func (vm *VM) withScope(action func()) {
 	saveScope := vm.curScope
 
@@ -927,14 +1683,27 @@ func (vm *VM) RunScriptReader(path string, f io.Reader) error {
 	if err != nil {
 		return err
 	}
+	vm.optimizeProgram(program)
 
 	vm.synCtx.PushFile(program.File)
 	defer vm.synCtx.PopFile(program.File)
-	return vm.runProgram(program)
+
+	if err := vm.runProgram(program); err != nil {
+		return err
+	}
+
+	vm.RunMicrotasks()
+	return nil
 }
 
 func ParseReader(path string, f io.Reader) (*ast.Program, error) {
-	program, err := parser.ParseFile(nil, path, f, 0)
+	src, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	src = preprocessBigIntLiterals(src)
+
+	program, err := parser.ParseFile(nil, path, bytes.NewReader(src), 0)
 	if err != nil {
 		msg := err.Error()
 		msg, found := strings.CutPrefix(msg, path)
@@ -970,9 +1739,107 @@ func (vm *VM) runProgram(program *ast.Program) error {
 	vm.curScope = &topScope
 	defer func() { vm.curScope = saveScope }()
 
+	vm.hoistVars(program.Body)
+
+	if !vm.bytecodeDisabled {
+		if script, err := CompileProgram(program.File, program); err == nil {
+			_, err := vm.RunCompiled(script)
+			return err
+		}
+		// Falls back to the tree-walker below for any construct the
+		// compiler doesn't cover yet, the same as Invoke's per-call fast
+		// path above.
+	}
+
 	return vm.runStmts(program.Body)
 }
 
+// hoistVars pre-binds every name collectHoistedVars finds in stmts to
+// undefined in the current scope, before stmts itself runs. Without this,
+// a reference to a var-declared name that lexically precedes its
+// declaring statement would reach DirectEnv.lookupVar before defineVar
+// ever ran for it locally, and lookupVar would silently walk past this
+// scope to find (or fail to find) the name in an enclosing one — instead
+// of correctly seeing the hoisted-but-not-yet-assigned undefined. Matches
+// Invoke/runProgram's call sites: defineVar's own "already defined"
+// check keeps this a no-op for names a parameter or prior hoist already
+// bound.
+func (vm *VM) hoistVars(stmts []ast.Statement) {
+	for _, name := range collectHoistedVars(stmts) {
+		vm.curScope.env.defineVar(vm.curScope, DeclVar, NameStr(name), JSUndefined{})
+	}
+}
+
+// collectHoistedVars walks stmts (not descending into nested
+// ast.FunctionLiteral/FunctionStatement bodies, which hoist through
+// their own scope instead) and returns every name a `var` declaration,
+// or a for-in loop's `var`-declared binder, introduces into this
+// function/program scope.
+func collectHoistedVars(stmts []ast.Statement) []string {
+	var names []string
+
+	var walkStmt func(stmt ast.Statement)
+	walkStmts := func(list []ast.Statement) {
+		for _, stmt := range list {
+			walkStmt(stmt)
+		}
+	}
+
+	walkStmt = func(stmt ast.Statement) {
+		switch s := stmt.(type) {
+		case *ast.BlockStatement:
+			walkStmts(s.List)
+		case *ast.VariableStatement:
+			for _, item := range s.List {
+				if ve, ok := item.(*ast.VariableExpression); ok {
+					names = append(names, ve.Name)
+				}
+			}
+		case *ast.IfStatement:
+			walkStmt(s.Consequent)
+			walkStmt(s.Alternate)
+		case *ast.WhileStatement:
+			walkStmt(s.Body)
+		case *ast.DoWhileStatement:
+			walkStmt(s.Body)
+		case *ast.ForStatement:
+			switch init := s.Initializer.(type) {
+			case *ast.VariableExpression:
+				names = append(names, init.Name)
+			case *ast.SequenceExpression:
+				for _, item := range init.Sequence {
+					if ve, ok := item.(*ast.VariableExpression); ok {
+						names = append(names, ve.Name)
+					}
+				}
+			}
+			walkStmt(s.Body)
+		case *ast.ForInStatement:
+			if ve, ok := s.Into.(*ast.VariableExpression); ok {
+				names = append(names, ve.Name)
+			}
+			walkStmt(s.Body)
+		case *ast.LabelledStatement:
+			walkStmt(s.Statement)
+		case *ast.WithStatement:
+			walkStmt(s.Body)
+		case *ast.TryStatement:
+			walkStmt(s.Body)
+			if s.Catch != nil {
+				walkStmt(s.Catch.Body)
+			}
+			walkStmt(s.Finally)
+		case *ast.SwitchStatement:
+			for _, c := range s.Body {
+				walkStmts(c.Consequent)
+			}
+		}
+	}
+
+	walkStmts(stmts)
+	return names
+}
+
 func (vm *VM) runStmts(stmts []ast.Statement) error {
 	for _, stmt := range stmts {
 		err := vm.runStmt(stmt)
@@ -1008,6 +1875,7 @@ func (vm *VM) runStmt(stmt ast.Statement) (err error) {
 
 	vm.synCtx.Push(stmt)
 	defer vm.synCtx.Pop(stmt)
+	vm.recordCoverage()
 
 	switch stmt := stmt.(type) {
 	case *ast.EmptyStatement:
@@ -1023,11 +1891,11 @@ func (vm *VM) runStmt(stmt ast.Statement) (err error) {
 			err = vm.runStmt(stmt.Body)
 		})
 
-		if exc, isExc := err.(*ProgramException); isExc {
+		if exc, isExc := err.(ProgramException); isExc {
 			if stmt.Catch != nil {
 				param := NameStr(stmt.Catch.Parameter.Name)
 				vm.withScope(func() {
-					vm.curScope.env.defineVar(vm.curScope, DeclVar, param, exc.exceptionValue)
+					vm.curScope.env.defineVar(vm.curScope, DeclVar, param, exc.bindCatchValue())
 					vm.curScope.doNotDelete[param] = struct{}{}
 					err = vm.runStmt(stmt.Catch.Body)
 				})
@@ -1157,36 +2025,67 @@ func (vm *VM) makeFunction(params *ast.ParameterList, body ast.Statement, opts F
 func (vm *VM) evalExpr(expr ast.Expression) (value JSValue, err error) {
 	vm.synCtx.Push(expr)
 	defer vm.synCtx.Pop(expr)
+	vm.recordCoverage()
 
 	switch expr := expr.(type) {
 	case *ast.AssignExpression:
-		value, err = vm.evalExpr(expr.Right)
-		if err != nil {
+		if expr.Operator == token.ASSIGN {
+			value, err = vm.evalExpr(expr.Right)
+			if err != nil {
+				return
+			}
+			err = doAssignment(vm, expr.Left, value)
 			return
 		}
 
-		switch expr.Operator {
-		case token.ASSIGN:
-			// nothing, we're done
+		// every other operator is read-modify-write: resolve the
+		// target's object/key once, read its current value, combine it
+		// with the right-hand side, then write the result back through
+		// the same (obj, key) pair rather than re-evaluating expr.Left.
+		get, set, err := vm.resolveAssignTarget(expr.Left)
+		if err != nil {
+			return nil, err
+		}
+
+		prevValue, err := get()
+		if err != nil {
+			return nil, err
+		}
 
+		switch expr.Operator {
 		case token.PLUS:
-			var prevValue JSValue
-			prevValue, err = vm.evalExpr(expr.Left)
+			var rhs JSValue
+			rhs, err = vm.evalExpr(expr.Right)
 			if err != nil {
 				return nil, err
 			}
-			value, err = addition(vm, prevValue, value)
+			value, err = addition(vm, prevValue, rhs)
+
+		case token.MINUS, token.MULTIPLY, token.SLASH, token.REMAINDER,
+			token.SHIFT_LEFT, token.SHIFT_RIGHT, token.UNSIGNED_SHIFT_RIGHT,
+			token.AND, token.OR, token.EXCLUSIVE_OR:
+			var rhs JSValue
+			rhs, err = vm.evalExpr(expr.Right)
 			if err != nil {
 				return nil, err
 			}
+			value, err = arithmeticOp(vm, prevValue, rhs, expr.Operator)
 
 		default:
-			err = fmt.Errorf("unsupported/unimplemented assignment operator: %s", expr.Operator)
-			return
+			// &&=, ||=, ??=, and **= aren't reachable here: otto's ES5
+			// parser (which predates logical assignment, `??`, and `**`)
+			// has no tokens or grammar to produce them, so expr.Operator
+			// can never hold one of these values.
+			return nil, fmt.Errorf("unsupported/unimplemented assignment operator: %s", expr.Operator)
+		}
+		if err != nil {
+			return nil, err
 		}
 
-		err = doAssignment(vm, expr.Left, value)
-		return
+		if err = set(value); err != nil {
+			return nil, err
+		}
+		return value, nil
 
 	case *ast.FunctionLiteral:
 		return defineFunction(vm, *expr)
@@ -1457,24 +2356,7 @@ func (vm *VM) evalExpr(expr ast.Expression) (value JSValue, err error) {
 			if err != nil {
 				return nil, err
 			}
-			switch arg.Category() {
-			case VObject, VNull:
-				return JSString("object"), nil
-			case VBigInt:
-				return JSString("bigint"), nil
-			case VBoolean:
-				return JSString("boolean"), nil
-			case VFunction:
-				return JSString("function"), nil
-			case VNumber:
-				return JSString("number"), nil
-			case VString:
-				return JSString("string"), nil
-			case VUndefined:
-				return JSString("undefined"), nil
-			default:
-				panic("unexpected modeledjs.JSVCategory")
-			}
+			return typeofString(arg), nil
 
 		case token.NOT:
 			arg, err := vm.evalExpr(expr.Operand)
@@ -1504,7 +2386,7 @@ func (vm *VM) evalExpr(expr ast.Expression) (value JSValue, err error) {
 			case JSNumber:
 				return JSNumber(-spec), nil
 			case JSBigInt:
-				return JSBigInt(-spec), nil
+				return NewBigInt(new(big.Int).Neg(spec.v)), nil
 			default:
 				panic("bug: coerceNumeric returned something other than number or bigint")
 			}
@@ -1515,6 +2397,50 @@ func (vm *VM) evalExpr(expr ast.Expression) (value JSValue, err error) {
 			value = JSUndefined{}
 			return
 
+		case token.INCREMENT, token.DECREMENT:
+			// prefix/postfix ++ and -- share the same read-modify-write
+			// path as a compound assignment: resolve the target once,
+			// read it, write back the incremented/decremented value, and
+			// return either the old value (postfix) or the new one.
+			get, set, err := vm.resolveAssignTarget(expr.Operand)
+			if err != nil {
+				return nil, err
+			}
+			oldValue, err := get()
+			if err != nil {
+				return nil, err
+			}
+			oldNum, err := vm.coerceNumeric(oldValue)
+			if err != nil {
+				return nil, err
+			}
+
+			var newNum JSValue
+			switch n := oldNum.(type) {
+			case JSNumber:
+				if expr.Operator == token.INCREMENT {
+					newNum = n + 1
+				} else {
+					newNum = n - 1
+				}
+			case JSBigInt:
+				if expr.Operator == token.INCREMENT {
+					newNum = NewBigInt(new(big.Int).Add(n.v, big.NewInt(1)))
+				} else {
+					newNum = NewBigInt(new(big.Int).Sub(n.v, big.NewInt(1)))
+				}
+			default:
+				panic("bug: coerceNumeric returned something other than number or bigint")
+			}
+
+			if err := set(newNum); err != nil {
+				return nil, err
+			}
+			if expr.Postfix {
+				return oldNum, nil
+			}
+			return newNum, nil
+
 		default:
 			return nil, vm.ThrowError("SyntaxError", "unsupported unary expression: "+expr.Operator.String())
 		}
@@ -1540,7 +2466,7 @@ func (vm *VM) evalExpr(expr ast.Expression) (value JSValue, err error) {
 			index := uint(key)
 			return leftObj.GetIndex(index)
 		case JSBigInt:
-			index := uint(key)
+			index := uint(key.v.Int64())
 			return leftObj.GetIndex(index)
 		case JSString:
 			return leftObj.GetProperty(NameStr(string(key)), vm)
@@ -1640,11 +2566,27 @@ func (vm *VM) evalExpr(expr ast.Expression) (value JSValue, err error) {
 	case *ast.NullLiteral:
 		return JSNull{}, nil
 	case *ast.NumberLiteral:
+		// otto's own parser never produces a NumberLiteral whose Literal
+		// ends in "n" (it has no BigInt-literal syntax); that suffix only
+		// appears on a literal the optimizer re-synthesized from a folded
+		// JSBigInt constant (see jsValueToLiteral). otto's parser does,
+		// however, represent an ordinary integer literal like "5" as an
+		// int64 Value rather than float64 as a parsing optimization — that
+		// has nothing to do with BigInt-ness, so it must not be used to
+		// decide it.
+		if strings.HasSuffix(expr.Literal, "n") {
+			switch spec := expr.Value.(type) {
+			case int64:
+				return bigIntFromInt64(spec), nil
+			default:
+				panic(fmt.Sprintf("invalid bigint literal value: %#v", expr.Value))
+			}
+		}
 		switch spec := expr.Value.(type) {
 		case float64:
 			return JSNumber(spec), nil
 		case int64:
-			return JSBigInt(spec), nil
+			return JSNumber(spec), nil
 		default:
 			panic(fmt.Sprintf("invalid number literal value: %#v", expr.Value))
 		}
@@ -1652,7 +2594,8 @@ func (vm *VM) evalExpr(expr ast.Expression) (value JSValue, err error) {
 	case *ast.StringLiteral:
 		return JSString(expr.Value), nil
 
-	// case *ast.RegExpLiteral:
+	case *ast.RegExpLiteral:
+		return vm.newRegExp(expr.Pattern, expr.Flags)
 
 	default:
 		// includes *ast.BadExpression
@@ -1751,30 +2694,42 @@ func arithmeticOp(vm *VM, l, r JSValue, op token.Token) (res JSValue, err error)
 		switch op {
 		// TODO: operator `**`
 		case token.MULTIPLY:
-			return li * ri, nil
+			return NewBigInt(new(big.Int).Mul(li.v, ri.v)), nil
 		case token.PLUS:
-			return li + ri, nil
+			return NewBigInt(new(big.Int).Add(li.v, ri.v)), nil
 		case token.MINUS:
-			return li - ri, nil
-		case token.SHIFT_LEFT:
-			return li << ri, nil
-		case token.SHIFT_RIGHT:
-			return li >> ri, nil
+			return NewBigInt(new(big.Int).Sub(li.v, ri.v)), nil
+		case token.SHIFT_LEFT, token.SHIFT_RIGHT:
+			if ri.v.Sign() < 0 {
+				err = vm.ThrowError("RangeError", "BigInt negative exponent")
+				return
+			}
+			shift := uint(ri.v.Uint64())
+			if op == token.SHIFT_LEFT {
+				return NewBigInt(new(big.Int).Lsh(li.v, shift)), nil
+			}
+			return NewBigInt(new(big.Int).Rsh(li.v, shift)), nil
+		case token.UNSIGNED_SHIFT_RIGHT:
+			err = vm.ThrowError("TypeError", "BigInts have no unsigned right shift, use >> instead")
+			return
 		case token.EXCLUSIVE_OR:
-			return li ^ ri, nil
+			return NewBigInt(new(big.Int).Xor(li.v, ri.v)), nil
 		case token.AND:
-			return li & ri, nil
+			return NewBigInt(new(big.Int).And(li.v, ri.v)), nil
 		case token.OR:
-			return li | ri, nil
+			return NewBigInt(new(big.Int).Or(li.v, ri.v)), nil
 		case token.SLASH:
-			if ri == 0 {
-				return JSNumber(math.Inf(+1)), nil
+			if ri.v.Sign() == 0 {
+				err = vm.ThrowError("RangeError", "Division by zero")
+				return
 			}
-			return li / ri, nil
+			return NewBigInt(new(big.Int).Quo(li.v, ri.v)), nil
 		case token.REMAINDER:
-			return li % ri, nil
-		case token.UNSIGNED_SHIFT_RIGHT:
-			return li >> ri, nil
+			if ri.v.Sign() == 0 {
+				err = vm.ThrowError("RangeError", "Division by zero")
+				return
+			}
+			return NewBigInt(new(big.Int).Rem(li.v, ri.v)), nil
 		default:
 			err = vm.ThrowError("SyntaxError", "unsupported/invalid arithmetic operator: "+op.String())
 			return
@@ -1914,15 +2869,101 @@ func doAssignment(vm *VM, target ast.Expression, value JSValue) error {
 
 }
 
+// resolveAssignTarget evaluates a read-modify-write assignment target's
+// object/key sub-expressions exactly once, returning a (get, set) pair
+// closed over them. Unlike doAssignment, which re-evaluates target.Left
+// to perform a plain `=` write, a compound or prefix/postfix target must
+// read the current value and later write the new one through the same
+// object without evaluating target.Left (e.g. `a.b` in `a.b.c -= 1`)
+// twice.
+func (vm *VM) resolveAssignTarget(target ast.Expression) (get func() (JSValue, error), set func(JSValue) error, err error) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		name := NameStr(target.Name)
+		get = func() (JSValue, error) {
+			value, found := vm.curScope.env.lookupVar(vm.curScope, name)
+			if !found {
+				return nil, vm.ThrowError("NameError", fmt.Sprintf("undefined variable: %s", target.Name))
+			}
+			return value, nil
+		}
+		set = func(value JSValue) error {
+			return vm.curScope.env.setVar(vm.curScope, name, value, vm)
+		}
+		return get, set, nil
+
+	case *ast.DotExpression:
+		objValue, err := vm.evalExpr(target.Left)
+		if err != nil {
+			return nil, nil, err
+		}
+		obj, err := vm.coerceToObject(objValue)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		name := NameStr(target.Identifier.Name)
+		get = func() (JSValue, error) { return obj.GetProperty(name, vm) }
+		set = func(value JSValue) error { return obj.SetProperty(name, value, vm) }
+		return get, set, nil
+
+	case *ast.BracketExpression:
+		objValue, err := vm.evalExpr(target.Left)
+		if err != nil {
+			return nil, nil, err
+		}
+		obj, err := vm.coerceToObject(objValue)
+		if err != nil {
+			return nil, nil, err
+		}
+		keyValue, err := vm.evalExpr(target.Member)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		get = func() (JSValue, error) {
+			switch key := keyValue.(type) {
+			case JSNumber:
+				return obj.GetIndex(uint(key))
+			case JSBigInt:
+				return obj.GetIndex(uint(key.v.Int64()))
+			case JSString:
+				return obj.GetProperty(NameStr(string(key)), vm)
+			default:
+				msg := fmt.Sprintf("invalid type for object key: %s", reflect.TypeOf(keyValue).String())
+				return nil, vm.ThrowError("TypeError", msg)
+			}
+		}
+		set = func(value JSValue) error {
+			switch key := keyValue.(type) {
+			case JSString:
+				return obj.SetProperty(NameStr(string(key)), value, vm)
+			case JSNumber:
+				obj.SetIndex(int(key), value)
+				return nil
+			default:
+				return fmt.Errorf("object index/property is neither number nor string")
+			}
+		}
+		return get, set, nil
+
+	default:
+		return nil, nil, fmt.Errorf("invalid or unsupported assignment target: %#v", target)
+	}
+}
+
 func (vm *VM) coerceToObject(value JSValue) (obj *JSObject, err error) {
 	var consName string
 
 	switch specific := value.(type) {
 	case JSBigInt:
-		// weird stupid case. why is BigInt not a constructor?
-		obj = new(JSObject)
-		*obj = NewJSObject(&ProtoBigint)
-		obj.primBigInt = specific
+		// BigInt is not a constructor (`new BigInt()` throws below), so
+		// boxing one can't go through it with isNew like the Number/
+		// Boolean/String branches do; build the same kind of box
+		// directly via the helper they share instead.
+		obj = boxPrimitive(&ProtoBigint, specific, func(o *JSObject, prim JSBigInt) {
+			o.primBigInt = prim
+		})
 		return
 
 	case JSNumber:
@@ -1952,10 +2993,37 @@ func (vm *VM) coerceToObject(value JSValue) (obj *JSObject, err error) {
 	return vm.DoNew(cons, []JSValue{value})
 }
 
+// typeofString implements the `typeof` operator's result for a value
+// that's already been evaluated, factored out so the optimizer's
+// constant-folded `typeof <literal>` can't drift from what evalExpr
+// computes for the real operator.
+func typeofString(v JSValue) JSString {
+	switch v.Category() {
+	case VObject, VNull:
+		return JSString("object")
+	case VBigInt:
+		return JSString("bigint")
+	case VBoolean:
+		return JSString("boolean")
+	case VFunction:
+		return JSString("function")
+	case VNumber:
+		return JSString("number")
+	case VString:
+		return JSString("string")
+	case VSymbol:
+		return JSString("symbol")
+	case VUndefined:
+		return JSString("undefined")
+	default:
+		panic("unexpected modeledjs.JSVCategory")
+	}
+}
+
 func (vm *VM) coerceToBoolean(value JSValue) JSBoolean {
 	switch spec := value.(type) {
 	case JSBigInt:
-		return spec != 0
+		return spec.v.Sign() != 0
 	case JSBoolean:
 		return spec
 	case JSNull:
@@ -1966,6 +3034,8 @@ func (vm *VM) coerceToBoolean(value JSValue) JSBoolean {
 		return true
 	case JSString:
 		return spec != ""
+	case *JSSymbol:
+		return true
 	case JSUndefined:
 		return false
 	default:
@@ -1980,7 +3050,7 @@ func (vm *VM) strictEqual(left, right JSValue) bool {
 		if !isSame {
 			return false
 		}
-		return leftV == rightV
+		return leftV.v.Cmp(rightV.v) == 0
 	case JSBoolean:
 		rightV, isSame := right.(JSBoolean)
 		if !isSame {
@@ -2012,6 +3082,12 @@ func (vm *VM) strictEqual(left, right JSValue) bool {
 	case JSUndefined:
 		_, isSame := right.(JSUndefined)
 		return isSame
+	case *JSSymbol:
+		rightV, isSame := right.(*JSSymbol)
+		if !isSame {
+			return false
+		}
+		return leftV == rightV
 
 	default:
 		panic(fmt.Sprintf("unexpected value for strict equal comparison: %#v", left))
@@ -2077,7 +3153,15 @@ func (vm *VM) looseEqual(a, b JSValue) (ret bool, err error) {
 			continue
 		}
 
-		// TODO Check for Symbol here
+		// Symbols never loosely-coerce to or from anything else (and the
+		// same-type case above already handled symbol == symbol), so a
+		// symbol reaching here against a non-symbol is simply unequal.
+		_, isASym := a.(*JSSymbol)
+		_, isBSym := b.(*JSSymbol)
+		if isASym || isBSym {
+			ret = false
+			return
+		}
 
 		// If one of the operands is a Boolean but the other is not,
 		// convert the boolean to a number: true is converted to 1, and
@@ -2142,11 +3226,11 @@ func (vm *VM) looseEqual(a, b JSValue) (ret bool, err error) {
 		}
 
 		if isANum && isBBigInt {
-			b = JSNumber(float64(int64(bi)))
+			b = JSNumber(bi.float64())
 			continue
 		}
 		if isABigInt && isBNum {
-			a = JSNumber(float64(int64(ai)))
+			a = JSNumber(ai.float64())
 			continue
 		}
 
@@ -2163,7 +3247,7 @@ func (vm *VM) looseEqual(a, b JSValue) (ret bool, err error) {
 }
 
 func (vm *VM) coerceNumeric(value JSValue) (num JSValue, err error) {
-	num, err = vm.coerceToPrimitive(value, PrimCoerceValueOfFirst)
+	num, err = vm.coerceToPrimitive(value, PrimCoerceNumberFirst)
 	if err != nil {
 		return
 	}
@@ -2200,7 +3284,7 @@ func (vm *VM) coerceToNumber(value JSValue) (num JSNumber, err error) {
 	case JSNull:
 		num = 0
 	case JSBigInt:
-		num = JSNumber(float64(int64(spec)))
+		num = JSNumber(spec.float64())
 	case JSBoolean:
 		if spec {
 			num = JSNumber(1.0)
@@ -2209,26 +3293,24 @@ func (vm *VM) coerceToNumber(value JSValue) (num JSNumber, err error) {
 		}
 
 	case JSNumber:
-		break
+		num = spec
 
 	case *JSObject:
 		var prim JSValue
-		prim, err = vm.coerceToPrimitive(value, PrimCoerceValueOfFirst)
+		prim, err = vm.coerceToPrimitive(value, PrimCoerceNumberFirst)
 		if err == nil {
 			num, err = vm.coerceToNumber(prim)
 		}
 
 	case JSString:
-		var numF64 float64
-		numF64, err = strconv.ParseFloat(string(spec), 64)
-		if err != nil {
-			err = vm.ThrowError("SyntaxError", err.Error())
-		}
-		num = JSNumber(numF64)
+		num = JSNumber(stringToNumber(string(spec)))
 
 	case JSUndefined:
 		num = JSNumber(math.NaN())
 
+	case *JSSymbol:
+		err = vm.ThrowError("TypeError", "cannot convert a Symbol value to a number")
+
 	default:
 		panic(fmt.Sprintf("unexpected modeledjs.JSValue: %#v", spec))
 	}
@@ -2239,27 +3321,66 @@ func (vm *VM) coerceToNumber(value JSValue) (num JSNumber, err error) {
 type PrimCoerceOrder uint8
 
 const (
+	// PrimCoerceValueOfFirst is the ES "default" hint: used wherever a
+	// value could become either a Number or a String (binary +, loose
+	// equality).
 	PrimCoerceValueOfFirst PrimCoerceOrder = iota
+	// PrimCoerceNumberFirst is the ES "number" hint: used wherever a
+	// value is coerced specifically towards a Number or BigInt.
+	PrimCoerceNumberFirst
+	// PrimCoerceToStringFirst is the ES "string" hint: used wherever a
+	// value is coerced specifically towards a String.
 	PrimCoerceToStringFirst
 )
 
+// toPrimitiveHint maps a PrimCoerceOrder to the hint string passed to a
+// @@toPrimitive method, per the ES ToPrimitive abstract operation.
+func (order PrimCoerceOrder) toPrimitiveHint() (string, error) {
+	switch order {
+	case PrimCoerceValueOfFirst:
+		return "default", nil
+	case PrimCoerceNumberFirst:
+		return "number", nil
+	case PrimCoerceToStringFirst:
+		return "string", nil
+	default:
+		return "", fmt.Errorf("invalid order (only allowed are PrimCoerceValueOfFirst, PrimCoerceNumberFirst, PrimCoerceToStringFirst)")
+	}
+}
+
 func (vm *VM) coerceToPrimitive(value JSValue, order PrimCoerceOrder) (prim JSValue, err error) {
 	switch spec := value.(type) {
 	case *JSObject:
-		// valToPrimitive, err := vm.globalObject.GetProperty(NameStr("Symbol"), vm)
-		// if err != nil {
-		// 	return nil, err
-		// }
-		// symToPrimitive, isSym := valToPrimitive.(JSSymbol)
+		toPrimVal, err := spec.GetProperty(SymbolName(vm.wellKnownSymbols.toPrimitive), vm)
+		if err != nil {
+			if _, isUndefProp := err.(ErrUndefinedProperty); !isUndefProp {
+				return nil, err
+			}
+			toPrimVal = JSUndefined{}
+		}
+		if toPrimFn, isObj := toPrimVal.(*JSObject); isObj && toPrimFn.funcPart != nil {
+			hint, err := order.toPrimitiveHint()
+			if err != nil {
+				return nil, err
+			}
+			ret, err := toPrimFn.Invoke(vm, value, []JSValue{JSString(hint)}, CallFlags{})
+			if err != nil {
+				return nil, err
+			}
+			if _, isObj := ret.(*JSObject); isObj {
+				return nil, vm.ThrowError("TypeError", "Symbol.toPrimitive: can't return an object")
+			}
+			return ret, nil
+		}
 
 		var callOrder []string
 		switch order {
-		case PrimCoerceValueOfFirst:
+		case PrimCoerceValueOfFirst, PrimCoerceNumberFirst:
 			callOrder = []string{"toString", "valueOf"}
 		case PrimCoerceToStringFirst:
 			callOrder = []string{"valueOf", "toString"}
 		default:
-			return nil, fmt.Errorf("invalid order (only allowed are PrimCoerceToStringFirst, PrimCoerceValueOfFirst)")
+			return nil, fmt.Errorf("invalid order (only allowed are PrimCoerceToStringFirst, PrimCoerceValueOfFirst, PrimCoerceNumberFirst)")
 		}
 
 		for _, methodName := range callOrder {
@@ -2297,7 +3418,12 @@ func (vm *VM) coerceToString(val JSValue) (ret JSString, err error) {
 	switch val := val.(type) {
 	case JSString:
 		return val, nil
-	// TODO case JSSymbol
+	case *JSSymbol:
+		// Implicit ToString (template literals, +, String concatenation
+		// of an object whose @@toPrimitive/toString returned a symbol,
+		// ...) is lenient and renders the symbol's description; only the
+		// explicit String() function enforces the spec's TypeError.
+		return JSString(val.String()), nil
 	case JSUndefined:
 		return "undefined", nil
 	case JSNull:
@@ -2309,11 +3435,9 @@ func (vm *VM) coerceToString(val JSValue) (ret JSString, err error) {
 			return "false", nil
 		}
 	case JSNumber:
-		s := fmt.Sprintf("%f", float64(val))
-		return JSString(s), nil
+		return JSString(numberToString(float64(val))), nil
 	case JSBigInt:
-		s := fmt.Sprintf("%d", int64(val))
-		return JSString(s), nil
+		return JSString(val.v.String()), nil
 	case *JSObject:
 		prim, err := vm.coerceToPrimitive(val, PrimCoerceToStringFirst)
 		if err != nil {
@@ -2331,7 +3455,7 @@ func (vm *VM) coerceToString(val JSValue) (ret JSString, err error) {
 
 func (vm *VM) coerceToBigInt(value JSValue) (ret JSBigInt, err error) {
 	if _, isObj := value.(*JSObject); isObj {
-		value, err = vm.coerceToPrimitive(value, PrimCoerceValueOfFirst)
+		value, err = vm.coerceToPrimitive(value, PrimCoerceNumberFirst)
 		if err != nil {
 			return
 		}
@@ -2341,22 +3465,24 @@ func (vm *VM) coerceToBigInt(value JSValue) (ret JSBigInt, err error) {
 	case JSBigInt:
 		ret = spec
 	case JSNumber:
-		ret = JSBigInt(int64(spec))
+		ret = bigIntFromInt64(int64(spec))
 	case JSBoolean:
 		if spec {
-			ret = 1
+			ret = bigIntFromInt64(1)
 		} else {
-			ret = 0
+			ret = bigIntFromInt64(0)
 		}
 	case JSString:
-		retI64, err := strconv.ParseInt(string(spec), 10, 64)
-		if err == nil {
-			ret = JSBigInt(retI64)
+		bi, ok := parseStringIntegerLiteral(string(spec))
+		if ok {
+			ret = NewBigInt(bi)
+		} else {
+			ret = bigIntFromInt64(0)
 		}
 
 	case JSNull:
-	case JSUndefined:
-		// case JSSymbol:
+		ret = bigIntFromInt64(0)
+	case JSUndefined, *JSSymbol:
 		err = vm.ThrowError("TypeError", "can't convert to BigInt from null, undefined or symbol")
 
 	default:
@@ -2394,13 +3520,16 @@ func compareLessThan(vm *VM, a, b JSValue) (ret tribool, err error) {
 
 	if aStr, isAStr := a.(JSString); isAStr {
 		if bStr, isBStr := b.(JSString); isBStr {
-			al := len(aStr)
-			bl := len(bStr)
+			// Abstract relational comparison of two strings is defined over
+			// UTF-16 code units, not raw UTF-8 bytes: comparing aStr[i]
+			// directly would order "é" (bytes 0xC3 0xA9) before "f".
+			aUnits := aStr.utf16Units()
+			bUnits := bStr.utf16Units()
 
-			limit := min(al, bl)
+			limit := min(len(aUnits), len(bUnits))
 			for i := 0; i < limit; i++ {
-				ac := aStr[i]
-				bc := bStr[i]
+				ac := aUnits[i]
+				bc := bUnits[i]
 				if ac < bc {
 					return TTrue, nil
 				}
@@ -2408,24 +3537,24 @@ func compareLessThan(vm *VM, a, b JSValue) (ret tribool, err error) {
 					return TFalse, nil
 				}
 			}
-			if al < bl {
+			if len(aUnits) < len(bUnits) {
 				return TTrue, nil
 			}
 			return TFalse, nil
 		} else if bBI, isBBigInt := b.(JSBigInt); isBBigInt {
-			aBI, err := strconv.ParseInt(string(aStr), 10, 64)
-			if err != nil {
+			aBI, ok := parseStringIntegerLiteral(string(aStr))
+			if !ok {
 				return TNeither, nil
 			}
-			return bool2tri(aBI < int64(bBI)), nil
+			return bool2tri(aBI.Cmp(bBI.v) < 0), nil
 		}
 	} else if aBI, isABigInt := a.(JSBigInt); isABigInt {
 		if bStr, isBStr := b.(JSString); isBStr {
-			bBI, err := strconv.ParseInt(string(bStr), 10, 64)
-			if err != nil {
+			bBI, ok := parseStringIntegerLiteral(string(bStr))
+			if !ok {
 				return TNeither, nil
 			}
-			return bool2tri(int64(aBI) < bBI), nil
+			return bool2tri(aBI.v.Cmp(bBI) < 0), nil
 		}
 	}
 
@@ -2468,18 +3597,18 @@ func compareLessThan(vm *VM, a, b JSValue) (ret tribool, err error) {
 			return bool2tri(an < bn), nil
 		} else if isBBigInt {
 			// replacing a with floor(a) does not influence the comparison
-			aFloor := int64(math.Floor(float64(an)))
-			return bool2tri(aFloor < int64(bi)), nil
+			aFloor := big.NewInt(int64(math.Floor(float64(an))))
+			return bool2tri(aFloor.Cmp(bi.v) < 0), nil
 		} else {
 			panic("bug: invalid type b from coerceNumeric")
 		}
 	} else if isABigInt {
 		if isBNum {
 			// replacing b with ceil(b) does not influence the comparison
-			bCeil := int64(math.Ceil(float64(bn)))
-			return bool2tri(int64(ai) < bCeil), nil
+			bCeil := big.NewInt(int64(math.Ceil(float64(bn))))
+			return bool2tri(ai.v.Cmp(bCeil) < 0), nil
 		} else if isBBigInt {
-			return bool2tri(ai < bi), nil
+			return bool2tri(ai.v.Cmp(bi.v) < 0), nil
 		} else {
 			panic("bug: invalid type b from coerceNumeric")
 		}
@@ -2533,24 +3662,71 @@ func (vm *VM) DoNew(cons *JSObject, args []JSValue) (obj *JSObject, err error) {
 
 func (vm *VM) ThrowError(className string, message string) error {
 	exc := NewJSObject(&ProtoObject)
-	err := exc.SetProperty(NameStr("message"), JSString(message), vm)
+	err := exc.SetProperty(NameStr("name"), JSString(className), vm)
+	if err == nil {
+		err = exc.SetProperty(NameStr("message"), JSString(message), vm)
+	}
 	if err != nil {
 		panic("SetProperty must not fail here!")
 	}
 	return ProgramException{
 		exceptionValue: &exc,
 		context:        vm.synCtx,
+		frames:         vm.captureFrames(),
 	}
 
 }
 
+// ErrorClassName returns the `name` property of a thrown value, if it is an
+// object with a string `name` (as produced by ThrowError). It returns "" for
+// any other kind of thrown value (e.g. a plain string or number thrown by
+// user code).
+func (pexc ProgramException) ErrorClassName() string {
+	excObj, isObj := pexc.exceptionValue.(*JSObject)
+	if !isObj {
+		return ""
+	}
+	nameValue, err := excObj.GetOwnProperty(NameStr("name"), nil)
+	if err != nil {
+		return ""
+	}
+	nameStr, isStr := nameValue.(JSString)
+	if !isStr {
+		return ""
+	}
+	return string(nameStr)
+}
+
 func (vm *VM) makeException(excValue JSValue) error {
 	return ProgramException{
 		exceptionValue: excValue,
 		context:        ProgramContext(vm.synCtx),
+		frames:         vm.captureFrames(),
 	}
 }
 
+// captureFrames builds the stack trace for an exception thrown right now:
+// one StackFrame per active call, innermost first. It walks ScopeCall.caller
+// rather than scope.parent, so it follows who dynamically called whom
+// instead of a closure's lexical scope chain.
+func (vm *VM) captureFrames() []StackFrame {
+	var frames []StackFrame
+	pos := vm.synCtx.currentPosition()
+
+	for scope := currentCall(vm.curScope); scope != nil; scope = scope.call.caller {
+		sc := scope.call
+		frame := StackFrame{Pos: pos}
+		if sc.funcPart != nil {
+			frame.FuncName = sc.funcPart.name
+			frame.File = sc.funcPart.file
+		}
+		frames = append(frames, frame)
+		pos = sc.callSite
+	}
+
+	return frames
+}
+
 func fixAndCheck(file *parserFile.File, node ast.Node) error {
 	chk := &checker{
 		file: file,
@@ -2566,10 +3742,106 @@ type checker struct {
 	file *parserFile.File
 	errs []error
 	ctx  []checkerContext
+
+	// funcDepth, breakables and pendingLabels give checker the same
+	// break/continue/return validation compiler.go's findBreakable/
+	// findContinuable already do for the bytecode path (see
+	// breakableCtx there), but run here unconditionally before either
+	// interpreter sees the program, so a `break nonexistent;` is a
+	// syntax error even when bytecode compilation later falls back to
+	// the tree-walker.
+	funcDepth     int
+	breakables    []*checkerBreakableCtx
+	pendingLabels []string
 }
 type checkerContext struct {
 	node      ast.Node
 	setStrict bool
+
+	// funcDepthPushed and breakablePushed record whether entering this
+	// node incremented funcDepth / pushed a checkerBreakableCtx, so the
+	// matching Exit knows whether to undo it — mirroring how setStrict
+	// is recorded per-frame above.
+	funcDepthPushed bool
+	breakablePushed bool
+}
+
+// checkerBreakableCtx is checker's break/continue bookkeeping, one per
+// enclosing loop, switch, or labeled block. funcDepth records how many
+// FunctionLiterals were open when it was pushed, so a break/continue
+// inside a nested function never resolves to a loop in an enclosing
+// one — the label scope compiler.go's breakableCtx gets for free by
+// being per-CompiledScript, checker has to track explicitly since one
+// checker walks a whole program including nested functions.
+type checkerBreakableCtx struct {
+	labels    []string
+	isLoop    bool
+	funcDepth int
+}
+
+// pushBreakable starts a new checkerBreakableCtx, claiming any labels
+// accumulated by LabelledStatements directly wrapping the node that's
+// pushing it (the same takePendingLabels handoff compiler.go uses).
+func (c *checker) pushBreakable(isLoop bool) {
+	labels := c.pendingLabels
+	c.pendingLabels = nil
+	c.breakables = append(c.breakables, &checkerBreakableCtx{
+		labels:    labels,
+		isLoop:    isLoop,
+		funcDepth: c.funcDepth,
+	})
+}
+
+// findBreakable resolves an unlabeled break to the innermost enclosing
+// loop/switch/labeled block, or a labeled break to the
+// checkerBreakableCtx carrying that label — in either case restricted
+// to the current function, since break can't reach outward across a
+// function boundary.
+func (c *checker) findBreakable(label string) (*checkerBreakableCtx, bool) {
+	for i := len(c.breakables) - 1; i >= 0; i-- {
+		ctx := c.breakables[i]
+		if ctx.funcDepth != c.funcDepth {
+			continue
+		}
+		if label == "" {
+			return ctx, true
+		}
+		for _, l := range ctx.labels {
+			if l == label {
+				return ctx, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// findContinuable is findBreakable's continue counterpart: continue
+// only ever targets a loop. A label that resolves to a non-loop
+// checkerBreakableCtx is reported separately (ECMAScript calls that out
+// as its own SyntaxError, distinct from "label not found").
+func (c *checker) findContinuable(label string) (ctx *checkerBreakableCtx, foundNonLoop bool, ok bool) {
+	for i := len(c.breakables) - 1; i >= 0; i-- {
+		cand := c.breakables[i]
+		if cand.funcDepth != c.funcDepth {
+			continue
+		}
+		if label == "" {
+			if cand.isLoop {
+				return cand, false, true
+			}
+			continue
+		}
+		for _, l := range cand.labels {
+			if l != label {
+				continue
+			}
+			if !cand.isLoop {
+				return nil, true, false
+			}
+			return cand, false, true
+		}
+	}
+	return nil, false, false
 }
 
 type multiSyntaxErrors []error
@@ -2664,12 +3936,70 @@ func (c *checker) Enter(node ast.Node) (v ast.Visitor) {
 
 	case *ast.ForStatement:
 		c.forbidFuncDecl(node.Body)
+		c.pushBreakable(true)
+		c.ctx[len(c.ctx)-1].breakablePushed = true
 	case *ast.ForInStatement:
 		c.forbidFuncDecl(node.Body)
+		c.pushBreakable(true)
+		c.ctx[len(c.ctx)-1].breakablePushed = true
 	case *ast.WhileStatement:
 		c.forbidFuncDecl(node.Body)
+		c.pushBreakable(true)
+		c.ctx[len(c.ctx)-1].breakablePushed = true
 	case *ast.DoWhileStatement:
 		c.forbidFuncDecl(node.Body)
+		c.pushBreakable(true)
+		c.ctx[len(c.ctx)-1].breakablePushed = true
+
+	case *ast.SwitchStatement:
+		c.pushBreakable(false)
+		c.ctx[len(c.ctx)-1].breakablePushed = true
+
+	case *ast.BlockStatement:
+		if len(c.pendingLabels) > 0 {
+			c.pushBreakable(false)
+			c.ctx[len(c.ctx)-1].breakablePushed = true
+		}
+
+	case *ast.LabelledStatement:
+		c.pendingLabels = append(c.pendingLabels, node.Label.Name)
+
+	case *ast.FunctionLiteral:
+		c.funcDepth++
+		c.ctx[len(c.ctx)-1].funcDepthPushed = true
+
+	case *ast.ReturnStatement:
+		if c.funcDepth == 0 {
+			c.emitErr("return statement is only valid inside a function")
+		}
+
+	case *ast.BranchStatement:
+		label := ""
+		if node.Label != nil {
+			label = node.Label.Name
+		}
+		if node.Token == token.BREAK {
+			if _, ok := c.findBreakable(label); !ok {
+				if label == "" {
+					c.emitErr("break statement is only valid inside a loop or switch statement")
+				} else {
+					c.emitErr(fmt.Sprintf("break label not found: %s", label))
+				}
+			}
+			break
+		}
+
+		_, foundNonLoop, ok := c.findContinuable(label)
+		switch {
+		case ok:
+			// resolved to a loop, nothing to report
+		case foundNonLoop:
+			c.emitErr(fmt.Sprintf("continue label does not label a loop: %s", label))
+		case label == "":
+			c.emitErr("continue statement is only valid inside a loop")
+		default:
+			c.emitErr(fmt.Sprintf("continue label not found: %s", label))
+		}
 	}
 
 	// keep using the same visitor
@@ -2707,9 +4037,24 @@ func isStrictReservedKw(s string) bool {
 }
 
 func (c *checker) Exit(node ast.Node) {
-	if c.ctx[len(c.ctx)-1].node != node {
+	top := c.ctx[len(c.ctx)-1]
+	if top.node != node {
 		panic("bug: fixAndCheck: inconsistent context")
 	}
 
+	if top.breakablePushed {
+		c.breakables = c.breakables[:len(c.breakables)-1]
+	}
+	if top.funcDepthPushed {
+		c.funcDepth--
+	}
+	if _, isLabelled := node.(*ast.LabelledStatement); isLabelled {
+		// Only a loop/switch/block directly wrapped by this label
+		// claims pendingLabels (via pushBreakable, above); anything
+		// else (e.g. "foo: x = 1;") leaves it unclaimed, so clear it
+		// here rather than let it leak onto an unrelated sibling.
+		c.pendingLabels = nil
+	}
+
 	c.ctx = c.ctx[:len(c.ctx)-1]
 }
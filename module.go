@@ -0,0 +1,856 @@
+package modeledjs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+)
+
+// ModuleKind says how a loaded Source should be compiled: as an ES module,
+// as a CommonJS script, or as a plain JSON document.
+type ModuleKind uint8
+
+const (
+	KindESM ModuleKind = iota
+	KindCJS
+	KindJSON
+)
+
+// Source is what a ModuleLoader hands back for a resolved specifier: the
+// raw bytes, plus enough information (Kind) to know how to compile them.
+type Source struct {
+	Bytes []byte
+	Kind  ModuleKind
+}
+
+// ModuleLoader resolves import/require specifiers to a stable module
+// identity and loads their contents. VM never touches the filesystem (or
+// a network, or a bundler's virtual filesystem) directly — it only goes
+// through whatever ModuleLoader is installed, so embedders can swap in
+// an in-memory loader for tests, a bundler-backed one, or (the common
+// case) FSLoader.
+type ModuleLoader interface {
+	// Resolve turns specifier, as written in an import/require at
+	// referrer, into a stable identifier (FSLoader uses an absolute
+	// path) that Load accepts and that doubles as the module's cache
+	// key. referrer is "" for the entry module, i.e. whatever vm.Import
+	// was called with directly.
+	Resolve(specifier, referrer string) (string, error)
+	// Load reads the module identified by resolved, as previously
+	// returned by Resolve.
+	Load(resolved string) (Source, error)
+}
+
+// FSLoader is the default ModuleLoader: Node-style resolution rooted at
+// BaseDir. Relative specifiers ("./foo", "../foo") resolve against the
+// referrer's directory (or BaseDir for the entry module); bare
+// specifiers ("foo", "foo/bar") are looked up by walking node_modules
+// directories from the referrer's directory up to the filesystem root,
+// same as Node's algorithm.
+type FSLoader struct {
+	BaseDir string
+}
+
+// NewFSLoader returns an FSLoader rooted at baseDir.
+func NewFSLoader(baseDir string) *FSLoader {
+	return &FSLoader{BaseDir: baseDir}
+}
+
+func (l *FSLoader) referrerDir(referrer string) string {
+	if referrer == "" {
+		return l.BaseDir
+	}
+	return filepath.Dir(referrer)
+}
+
+func (l *FSLoader) Resolve(specifier, referrer string) (string, error) {
+	if strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") || filepath.IsAbs(specifier) {
+		base := specifier
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(l.referrerDir(referrer), specifier)
+		}
+		if resolved, ok := resolveFileOrDir(base); ok {
+			return resolved, nil
+		}
+		return "", fmt.Errorf("module not found: %s (relative to %s)", specifier, referrer)
+	}
+
+	for dir := l.referrerDir(referrer); ; {
+		candidate := filepath.Join(dir, "node_modules", specifier)
+		if resolved, ok := l.resolvePackage(candidate); ok {
+			return resolved, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return "", fmt.Errorf("module not found: %s (from %s)", specifier, referrer)
+}
+
+// resolveFileOrDir resolves base to a concrete file: base itself if it's
+// already a file, base plus a recognized extension, or (if base is a
+// directory) that directory treated as a package via resolvePackage.
+func resolveFileOrDir(base string) (string, bool) {
+	if info, err := os.Stat(base); err == nil && !info.IsDir() {
+		return base, true
+	}
+
+	for _, ext := range []string{".js", ".mjs", ".cjs", ".json"} {
+		if info, err := os.Stat(base + ext); err == nil && !info.IsDir() {
+			return base + ext, true
+		}
+	}
+
+	if info, err := os.Stat(base); err == nil && info.IsDir() {
+		return (&FSLoader{}).resolvePackage(base)
+	}
+
+	return "", false
+}
+
+// resolvePackage resolves dir (either a node_modules/<name> directory or
+// any other directory being imported directly) via its package.json
+// "exports"/"main" field, falling back to index.js.
+func (l *FSLoader) resolvePackage(dir string) (string, bool) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		if resolved, ok := resolveFileOrDir(dir); ok {
+			return resolved, true
+		}
+		return "", false
+	}
+	if !info.IsDir() {
+		return dir, true
+	}
+
+	if pkg, ok := readPackageJSON(dir); ok {
+		if entry, ok := pkg.entryPoint(); ok {
+			entryPath := filepath.Join(dir, entry)
+			if resolved, ok := resolveFileOrDir(entryPath); ok {
+				return resolved, true
+			}
+		}
+	}
+
+	return resolveFileOrDir(filepath.Join(dir, "index"))
+}
+
+// packageJSON is the handful of package.json fields Node-style resolution
+// and module-kind detection actually need.
+type packageJSON struct {
+	Main    string `json:"main"`
+	Type    string `json:"type"`
+	Exports any    `json:"exports"`
+}
+
+func readPackageJSON(dir string) (packageJSON, bool) {
+	var pkg packageJSON
+	bytes, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return pkg, false
+	}
+	if err := json.Unmarshal(bytes, &pkg); err != nil {
+		return pkg, false
+	}
+	return pkg, true
+}
+
+// entryPoint returns the package's main entry file, preferring the
+// string form of "exports" (either "." itself or the top-level "." entry
+// of a conditional exports map) over "main", matching the order Node
+// tries them in.
+func (pkg packageJSON) entryPoint() (string, bool) {
+	switch exp := pkg.Exports.(type) {
+	case string:
+		return exp, true
+	case map[string]any:
+		if dot, ok := exp["."]; ok {
+			if s, ok := dot.(string); ok {
+				return s, true
+			}
+		}
+	}
+	if pkg.Main != "" {
+		return pkg.Main, true
+	}
+	return "", false
+}
+
+func (l *FSLoader) Load(resolved string) (Source, error) {
+	bytes, err := os.ReadFile(resolved)
+	if err != nil {
+		return Source{}, err
+	}
+	return Source{Bytes: bytes, Kind: l.kindOf(resolved)}, nil
+}
+
+func (l *FSLoader) kindOf(resolved string) ModuleKind {
+	switch filepath.Ext(resolved) {
+	case ".json":
+		return KindJSON
+	case ".mjs":
+		return KindESM
+	case ".cjs":
+		return KindCJS
+	}
+
+	for dir := filepath.Dir(resolved); ; {
+		if pkg, ok := readPackageJSON(dir); ok {
+			if pkg.Type == "module" {
+				return KindESM
+			}
+			return KindCJS
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return KindCJS
+}
+
+// moduleImportRef is one binding a module's Environment resolves by
+// proxying into the exporting module's own scope, rather than storing a
+// value locally: that's what makes `import { x } from ...` a live
+// binding instead of a snapshot taken at import time.
+type moduleImportRef struct {
+	scope *Scope
+	name  Name
+}
+
+// moduleEnv is the Environment installed as a linked ES module's
+// top-level scope. Locally declared bindings (including the module's own
+// exports) behave like any other DirectEnv; names recorded in imports
+// instead forward every read straight to the exporting module's scope,
+// so assignments the exporter makes to an exported `let` are visible
+// here without re-running anything.
+type moduleEnv struct {
+	local   DirectEnv
+	imports map[Name]moduleImportRef
+
+	// namespaces holds `import * as ns` bindings: ns's value is the
+	// dependency's namespace object, built (and cached) on first lookup
+	// rather than at link time, since the dependency hasn't evaluated
+	// yet when linking runs and building it early would freeze every
+	// export at its pre-evaluation (undefined) value.
+	namespaces map[Name]*ModuleRecord
+}
+
+func newModuleEnv() moduleEnv {
+	return moduleEnv{
+		local:      make(DirectEnv),
+		imports:    make(map[Name]moduleImportRef),
+		namespaces: make(map[Name]*ModuleRecord),
+	}
+}
+
+func (me moduleEnv) defineVar(scope *Scope, kind DeclKind, name Name, value JSValue) {
+	me.local.defineVar(scope, kind, name, value)
+}
+
+func (me moduleEnv) setVar(scope *Scope, name Name, value JSValue, vm *VM) error {
+	if _, isImport := me.imports[name]; isImport {
+		return vm.ThrowError("TypeError", "Assignment to an imported binding: "+name.String())
+	}
+	if _, isNamespace := me.namespaces[name]; isNamespace {
+		return vm.ThrowError("TypeError", "Assignment to an imported binding: "+name.String())
+	}
+	return me.local.setVar(scope, name, value, vm)
+}
+
+func (me moduleEnv) lookupVar(scope *Scope, name Name) (JSValue, bool) {
+	if ref, isImport := me.imports[name]; isImport {
+		return ref.scope.env.lookupVar(ref.scope, ref.name)
+	}
+	if dep, isNamespace := me.namespaces[name]; isNamespace {
+		return dep.namespaceObject(), true
+	}
+	return me.local.lookupVar(scope, name)
+}
+
+func (me moduleEnv) deleteVar(scope *Scope, name Name) bool {
+	return false
+}
+
+// moduleState tracks a ModuleRecord through the three phases import
+// graphs go through: parsed-but-not-linked (so a cycle can at least be
+// detected instead of recursing forever), linked-but-not-evaluated, and
+// evaluated.
+type moduleState uint8
+
+const (
+	moduleLoaded moduleState = iota
+	moduleLinked
+	moduleEvaluated
+)
+
+// ModuleRecord is one node of an import graph: a resolved, loaded module
+// together with whatever linking/evaluation state it has reached so far.
+// VM keeps exactly one ModuleRecord per resolved path, so two importers
+// of the same file share the same bindings and the same module-level
+// side effects run only once.
+type ModuleRecord struct {
+	resolved string
+	kind     ModuleKind
+	state    moduleState
+
+	program     *ast.Program // ESM only
+	importDecls []moduleImportDecl
+	exportLocal map[string]Name // exported name -> local binding name
+	scope       *Scope          // module's own top-level scope, created at parse time so imports can bind to it before evaluation runs
+
+	namespace *JSObject
+
+	jsonValue  JSValue // KindJSON only
+	cjsExports JSValue // KindCJS only, set once evaluation finishes
+	cjsModule  *JSObject
+}
+
+// moduleImportDecl is one `import ... from "specifier"` this module's
+// source contains, as found by the ESM pre-pass.
+type moduleImportDecl struct {
+	specifier string
+	bindings  []moduleImportBinding
+}
+
+// moduleImportBinding is one name bound by a moduleImportDecl: Local is
+// the name usable in this module's body, Imported is the name it's
+// exported as in the dependency ("default" for a default import, "*"
+// for a namespace import).
+type moduleImportBinding struct {
+	Imported string
+	Local    string
+}
+
+// moduleBaseScope returns the Scope every module's own top-level scope
+// is parented to: a scope over the VM's global object, same as any
+// script's top-level scope (see runProgram), created once and reused so
+// modules don't each get their own disconnected view of the globals.
+func (vm *VM) moduleBaseScope() *Scope {
+	if vm.moduleGlobalScope == nil {
+		s := newScope(ObjectEnv{&vm.globalObject})
+		vm.moduleGlobalScope = &s
+	}
+	return vm.moduleGlobalScope
+}
+
+// Import resolves and runs specifier as the entry point of an import
+// graph, returning its module namespace object (the same shape
+// `import * as ns from specifier` would bind ns to).
+func (vm *VM) Import(specifier string) (JSValue, error) {
+	record, err := vm.importFrom(specifier, "")
+	if err != nil {
+		return nil, err
+	}
+	return record.namespaceObject(), nil
+}
+
+func (vm *VM) importFrom(specifier, referrer string) (*ModuleRecord, error) {
+	if vm.loader == nil {
+		return nil, fmt.Errorf("modeledjs: no ModuleLoader configured; set VMOptions.ModuleBaseDir or call SetModuleLoader")
+	}
+
+	resolved, err := vm.loader.Resolve(specifier, referrer)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := vm.loadModule(resolved)
+	if err != nil {
+		return nil, err
+	}
+	if err := vm.linkModule(record); err != nil {
+		return nil, err
+	}
+	if err := vm.evaluateModule(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// SetModuleLoader installs loader as the ModuleLoader vm.Import and the
+// require() global resolve specifiers through.
+func (vm *VM) SetModuleLoader(loader ModuleLoader) {
+	vm.loader = loader
+}
+
+// loadModule reads and parses resolved, registering a ModuleRecord
+// before recursing into its dependencies so an import cycle finds the
+// in-progress record instead of looping forever.
+func (vm *VM) loadModule(resolved string) (*ModuleRecord, error) {
+	if record, ok := vm.modules[resolved]; ok {
+		return record, nil
+	}
+
+	src, err := vm.loader.Load(resolved)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &ModuleRecord{resolved: resolved, kind: src.Kind}
+	vm.modules[resolved] = record
+
+	switch src.Kind {
+	case KindJSON:
+		value, err := jsonBytesToJSValue(src.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", resolved, err)
+		}
+		record.jsonValue = value
+
+	case KindCJS:
+		// CJS has no static import graph to walk: require() calls are
+		// resolved and evaluated lazily, when the script itself runs
+		// (see requireFrom).
+
+	default: // KindESM
+		transformed, imports, exportLocal := preprocessESModule(string(src.Bytes))
+		program, err := ParseReader(resolved, strings.NewReader(transformed))
+		if err != nil {
+			return nil, err
+		}
+		vm.optimizeProgram(program)
+		record.program = program
+		record.importDecls = imports
+		record.exportLocal = exportLocal
+
+		moduleScope := newScope(newModuleEnv())
+		moduleScope.parent = vm.moduleBaseScope()
+		// Without a ScopeCall, DirectEnv.defineVar treats a `var` here as
+		// belonging to some enclosing scope and hoists it there (see
+		// Scope.call's doc comment) — exactly what a function's wrapper
+		// scope wants, but wrong for a module top level, which must be
+		// its own hoisting boundary instead of leaking into the shared
+		// global object every other module and script sees.
+		moduleScope.call = &ScopeCall{this: JSUndefined{}}
+		record.scope = &moduleScope
+
+		for _, decl := range imports {
+			depResolved, err := vm.loader.Resolve(decl.specifier, resolved)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := vm.loadModule(depResolved); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return record, nil
+}
+
+// linkModule wires record's import bindings to their dependencies' own
+// scopes. It assumes loadModule has already loaded every direct and
+// transitive dependency (true whenever linkModule is reached via
+// importFrom, since loadModule recurses eagerly).
+func (vm *VM) linkModule(record *ModuleRecord) error {
+	if record.state >= moduleLinked {
+		return nil
+	}
+	record.state = moduleLinked
+
+	if record.kind != KindESM {
+		return nil
+	}
+
+	env := record.scope.env.(moduleEnv)
+	for _, decl := range record.importDecls {
+		resolved, err := vm.loader.Resolve(decl.specifier, record.resolved)
+		if err != nil {
+			return err
+		}
+		dep, err := vm.loadModule(resolved)
+		if err != nil {
+			return err
+		}
+		if err := vm.linkModule(dep); err != nil {
+			return err
+		}
+
+		for _, binding := range decl.bindings {
+			if binding.Imported == "*" {
+				env.namespaces[NameStr(binding.Local)] = dep
+				continue
+			}
+
+			ref, err := dep.bindingFor(binding.Imported)
+			if err != nil {
+				return fmt.Errorf("%s: %w", record.resolved, err)
+			}
+			env.imports[NameStr(binding.Local)] = ref
+		}
+	}
+
+	return nil
+}
+
+// bindingFor returns the moduleImportRef an importer should use to read
+// the export named exported from this module.
+func (dep *ModuleRecord) bindingFor(exported string) (moduleImportRef, error) {
+	switch dep.kind {
+	case KindESM:
+		if exported == "*" {
+			return moduleImportRef{}, fmt.Errorf("import * as ns is exposed via the namespace object, not a live binding")
+		}
+		local, ok := dep.exportLocal[exported]
+		if !ok {
+			return moduleImportRef{}, fmt.Errorf("module %q has no export named %q", dep.resolved, exported)
+		}
+		return moduleImportRef{scope: dep.scope, name: local}, nil
+
+	default:
+		// CJS/JSON modules have no live scope to bind into; their value
+		// only exists once evaluation has produced it. Importers of a
+		// CJS/JSON dependency's named/default export read the already-
+		// evaluated module.exports/JSON value through the namespace
+		// object instead (see evaluateModule), so this path is reserved
+		// for future `import def from "./cjs-or-json-file"` support.
+		return moduleImportRef{}, fmt.Errorf("named/default live bindings into non-ESM module %q are not supported; import the namespace instead", dep.resolved)
+	}
+}
+
+// evaluateModule runs record's top-level body (post-order: dependencies
+// evaluate first), exactly once per record regardless of how many
+// importers reach it.
+func (vm *VM) evaluateModule(record *ModuleRecord) error {
+	if record.state >= moduleEvaluated {
+		return nil
+	}
+
+	switch record.kind {
+	case KindJSON:
+		record.state = moduleEvaluated
+		return nil
+
+	case KindCJS:
+		if err := vm.evaluateCJSModule(record); err != nil {
+			return err
+		}
+		record.state = moduleEvaluated
+		return nil
+
+	default: // KindESM
+		for _, decl := range record.importDecls {
+			resolved, err := vm.loader.Resolve(decl.specifier, record.resolved)
+			if err != nil {
+				return err
+			}
+			dep := vm.modules[resolved]
+			if err := vm.evaluateModule(dep); err != nil {
+				return err
+			}
+		}
+
+		record.state = moduleEvaluated
+
+		saveScope := vm.curScope
+		vm.curScope = record.scope
+		vm.synCtx.PushFile(record.program.File)
+		env := record.scope.env.(moduleEnv)
+		env.defineVar(record.scope, DeclConst, NameStr("require"), vm.makeRequireFunction(record.resolved))
+		env.defineVar(record.scope, DeclConst, NameStr("__importMetaUrl"), JSString("file://"+record.resolved))
+		err := vm.runStmts(record.program.Body)
+		vm.synCtx.PopFile(record.program.File)
+		vm.curScope = saveScope
+		return err
+	}
+}
+
+// namespaceObject returns record's module namespace object (what
+// `import * as ns` binds and what Import returns), building it the first
+// time it's asked for and reusing it after that: per spec, a module has
+// exactly one namespace object.
+func (record *ModuleRecord) namespaceObject() *JSObject {
+	if record.namespace != nil {
+		return record.namespace
+	}
+
+	ns := new(JSObject)
+	*ns = NewJSObject(nil)
+
+	switch record.kind {
+	case KindJSON:
+		ns.DefineProperty(NameStr("default"), Descriptor{value: record.jsonValue, enumerable: true})
+
+	case KindCJS:
+		ns.DefineProperty(NameStr("default"), Descriptor{value: record.cjsExports, enumerable: true})
+
+	default: // KindESM
+		for exported, local := range record.exportLocal {
+			value, _ := record.scope.env.lookupVar(record.scope, local)
+			ns.DefineProperty(NameStr(exported), Descriptor{value: value, enumerable: true})
+		}
+	}
+
+	// Own properties are fixed once the namespace object is built: no
+	// further exports can appear later, per spec.
+	ns.preventExtensions()
+
+	record.namespace = ns
+	return ns
+}
+
+// evaluateCJSModule runs a CommonJS module's source the way Node's
+// module wrapper does: `module`, `exports`, `require`, `__filename` and
+// `__dirname` are bound as locals of a fresh top-level scope, and
+// whatever `module.exports` holds afterward is the module's value.
+func (vm *VM) evaluateCJSModule(record *ModuleRecord) error {
+	src, err := vm.loader.Load(record.resolved)
+	if err != nil {
+		return err
+	}
+
+	program, err := ParseReader(record.resolved, strings.NewReader(string(src.Bytes)))
+	if err != nil {
+		return err
+	}
+	vm.optimizeProgram(program)
+
+	moduleObj := new(JSObject)
+	*moduleObj = NewJSObject(&ProtoObject)
+	exportsObj := new(JSObject)
+	*exportsObj = NewJSObject(&ProtoObject)
+	moduleObj.SetProperty(NameStr("exports"), exportsObj, nil)
+	record.cjsModule = moduleObj
+
+	cjsScope := newVarScope()
+	cjsScope.parent = vm.moduleBaseScope()
+	// Same reasoning as moduleScope's ScopeCall in loadModule: without
+	// it, `var` in this module's top-level code would hoist into the
+	// shared global object instead of staying local to the module.
+	cjsScope.call = &ScopeCall{this: JSUndefined{}}
+	cjsScope.env.defineVar(&cjsScope, DeclVar, NameStr("module"), moduleObj)
+	cjsScope.env.defineVar(&cjsScope, DeclVar, NameStr("exports"), exportsObj)
+	cjsScope.env.defineVar(&cjsScope, DeclVar, NameStr("require"), vm.makeRequireFunction(record.resolved))
+	cjsScope.env.defineVar(&cjsScope, DeclVar, NameStr("__filename"), JSString(record.resolved))
+	cjsScope.env.defineVar(&cjsScope, DeclVar, NameStr("__dirname"), JSString(filepath.Dir(record.resolved)))
+
+	saveScope := vm.curScope
+	vm.curScope = &cjsScope
+	vm.synCtx.PushFile(program.File)
+	err = vm.runStmts(program.Body)
+	vm.synCtx.PopFile(program.File)
+	vm.curScope = saveScope
+	if err != nil {
+		return err
+	}
+
+	value, _ := moduleObj.GetProperty(NameStr("exports"), nil)
+	record.cjsExports = value
+	return nil
+}
+
+// requireFromCurrentFile implements the global `require` exposed to
+// ordinary (non-module) scripts: the referrer is whatever file is
+// currently executing, per vm.synCtx, rather than one fixed at the point
+// a per-module require() (see makeRequireFunction) was created.
+func (vm *VM) requireFromCurrentFile(specifier string) (JSValue, error) {
+	referrer := ""
+	if n := len(vm.synCtx.fileStack); n > 0 {
+		referrer = vm.synCtx.fileStack[n-1].Name()
+	}
+	return vm.requireFrom(specifier, referrer)
+}
+
+// requireFrom is the shared body of every require() exposed by this
+// package (the global one and every module-scoped one makeRequireFunction
+// creates): synchronous resolution+evaluation of specifier relative to
+// referrer, returning module.exports for a CJS/JSON dependency or the
+// namespace object for an ESM one (the same default-export synthesis
+// Node's ESM/CJS interop does in reverse).
+func (vm *VM) requireFrom(specifier, referrer string) (JSValue, error) {
+	record, err := vm.importFrom(specifier, referrer)
+	if err != nil {
+		return nil, vm.ThrowError("Error", err.Error())
+	}
+
+	switch record.kind {
+	case KindCJS, KindJSON:
+		return record.namespaceObject().GetProperty(NameStr("default"), vm)
+	default:
+		return record.namespaceObject(), nil
+	}
+}
+
+// makeRequireFunction returns the require() implementation installed
+// into a module's scope, bound to referrer so relative specifiers
+// resolve against that module's own location.
+func (vm *VM) makeRequireFunction(referrer string) *JSObject {
+	fn := NewNativeFunction([]string{"specifier"}, func(vm *VM, _ JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		if len(args) == 0 {
+			return nil, vm.ThrowError("TypeError", "require: missing specifier argument")
+		}
+		specifier, err := vm.coerceToString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return vm.requireFrom(string(specifier), referrer)
+	})
+	return &fn
+}
+
+// jsonBytesToJSValue decodes bytes as JSON into the JSValue shapes this
+// package already uses for objects/arrays/primitives, the same
+// conversion a future JSON.parse would need; kept local to module.go
+// until that lands.
+func jsonBytesToJSValue(bytes []byte) (JSValue, error) {
+	var decoded any
+	if err := json.Unmarshal(bytes, &decoded); err != nil {
+		return nil, err
+	}
+	return goValueToJSValue(decoded), nil
+}
+
+func goValueToJSValue(v any) JSValue {
+	switch val := v.(type) {
+	case nil:
+		return JSNull{}
+	case bool:
+		return JSBoolean(val)
+	case float64:
+		return JSNumber(val)
+	case string:
+		return JSString(val)
+	case []any:
+		arr := NewJSArray()
+		for i, elem := range val {
+			arr.SetIndex(i, goValueToJSValue(elem))
+		}
+		return arr
+	case map[string]any:
+		obj := new(JSObject)
+		*obj = NewJSObject(&ProtoObject)
+		for k, elem := range val {
+			obj.SetProperty(NameStr(k), goValueToJSValue(elem), nil)
+		}
+		return obj
+	default:
+		return JSUndefined{}
+	}
+}
+
+// The otto parser (ES5) has no import/export AST nodes, so recognizing
+// module declarations happens as a textual pre-pass instead of at the
+// AST level: each whole-line import/export statement is matched by
+// regexp, recorded, and rewritten into something ES5 can parse (a plain
+// declaration, or nothing at all), before the result is handed to
+// ParseReader. This only covers the common single-line forms; anything
+// split across lines or using destructuring-in-import syntax is outside
+// today's subset, same spirit as compiler.go's "unsupported construct"
+// errors rather than a silent miscompile.
+var (
+	reImportNamed     = regexp.MustCompile(`^(\s*)import\s*\{([^}]*)\}\s*from\s*['"]([^'"]+)['"]\s*;?\s*$`)
+	reImportDefault   = regexp.MustCompile(`^(\s*)import\s+(\w+)\s*,?\s*(?:\{([^}]*)\})?\s*from\s*['"]([^'"]+)['"]\s*;?\s*$`)
+	reImportNamespace = regexp.MustCompile(`^(\s*)import\s*\*\s*as\s+(\w+)\s*from\s*['"]([^'"]+)['"]\s*;?\s*$`)
+	reImportBare      = regexp.MustCompile(`^(\s*)import\s*['"]([^'"]+)['"]\s*;?\s*$`)
+	reExportDefault   = regexp.MustCompile(`^(\s*)export\s+default\s+(.*)$`)
+	reExportNamed     = regexp.MustCompile(`^(\s*)export\s*\{([^}]*)\}\s*;?\s*$`)
+	reExportDecl      = regexp.MustCompile(`^(\s*)export\s+(const|let|var)\s+(\w+)(\s*=.*)$`)
+	reExportFunction  = regexp.MustCompile(`^(\s*)export\s+function\s+(\w+)`)
+)
+
+// preprocessESModule strips import/export syntax out of src, returning
+// plain ES5 source alongside the import declarations and the exported-
+// name -> local-name map the pre-pass found. An `export default`
+// expression is bound to __default_export__, recorded in that map under
+// "default".
+func preprocessESModule(src string) (transformed string, imports []moduleImportDecl, exportLocal map[string]Name) {
+	exportLocal = make(map[string]Name)
+	lines := strings.Split(src, "\n")
+	out := make([]string, len(lines))
+
+	for i, line := range lines {
+		switch {
+		case reImportNamespace.MatchString(line):
+			m := reImportNamespace.FindStringSubmatch(line)
+			// `import * as ns` is a namespace import; it has no live
+			// per-name binding, so linking binds it through
+			// moduleEnv.namespaces (the Imported == "*" check) rather
+			// than as an imported name.
+			imports = append(imports, moduleImportDecl{specifier: m[3], bindings: []moduleImportBinding{{Imported: "*", Local: m[2]}}})
+			out[i] = m[1]
+
+		case reImportNamed.MatchString(line):
+			m := reImportNamed.FindStringSubmatch(line)
+			imports = append(imports, moduleImportDecl{specifier: m[3], bindings: parseImportClause(m[2])})
+			out[i] = m[1]
+
+		case reImportDefault.MatchString(line):
+			m := reImportDefault.FindStringSubmatch(line)
+			bindings := []moduleImportBinding{{Imported: "default", Local: m[2]}}
+			if m[3] != "" {
+				bindings = append(bindings, parseImportClause(m[3])...)
+			}
+			imports = append(imports, moduleImportDecl{specifier: m[4], bindings: bindings})
+			out[i] = m[1]
+
+		case reImportBare.MatchString(line):
+			m := reImportBare.FindStringSubmatch(line)
+			imports = append(imports, moduleImportDecl{specifier: m[2]})
+			out[i] = m[1]
+
+		case reExportDefault.MatchString(line):
+			m := reExportDefault.FindStringSubmatch(line)
+			defaultName := NameStr("__default_export__")
+			out[i] = fmt.Sprintf("%svar %s = %s", m[1], defaultName.String(), m[2])
+			exportLocal["default"] = defaultName
+
+		case reExportNamed.MatchString(line):
+			m := reExportNamed.FindStringSubmatch(line)
+			for _, binding := range parseImportClause(m[2]) {
+				exportLocal[binding.Local] = NameStr(binding.Imported)
+			}
+			out[i] = m[1]
+
+		case reExportDecl.MatchString(line):
+			m := reExportDecl.FindStringSubmatch(line)
+			exportLocal[m[3]] = NameStr(m[3])
+			out[i] = fmt.Sprintf("%s%s %s%s", m[1], m[2], m[3], m[4])
+
+		case reExportFunction.MatchString(line):
+			m := reExportFunction.FindStringSubmatch(line)
+			exportLocal[m[2]] = NameStr(m[2])
+			out[i] = strings.Replace(line, "export function", "function", 1)
+
+		default:
+			out[i] = line
+		}
+	}
+
+	transformed = strings.ReplaceAll(strings.Join(out, "\n"), "import.meta.url", "__importMetaUrl")
+	return transformed, imports, exportLocal
+}
+
+// parseImportClause parses the inside of an `import { ... }` or
+// `export { ... }` clause: comma-separated `name` or `name as alias`
+// items. For an import clause, Imported is the name as exported by the
+// dependency and Local is the name bound here; for an export clause the
+// same struct is reused with Imported holding the module's own binding and
+// Local holding the name it's exported as.
+func parseImportClause(clause string) []moduleImportBinding {
+	var bindings []moduleImportBinding
+	for _, item := range strings.Split(clause, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Fields(item)
+		switch len(parts) {
+		case 1:
+			bindings = append(bindings, moduleImportBinding{Imported: parts[0], Local: parts[0]})
+		case 3: // name "as" alias
+			bindings = append(bindings, moduleImportBinding{Imported: parts[0], Local: parts[2]})
+		}
+	}
+	return bindings
+}
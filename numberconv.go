@@ -0,0 +1,190 @@
+package modeledjs
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// strDecimalLiteralRE matches the ES StrDecimalLiteral grammar, minus the
+// optional leading sign and the "Infinity" alternative (both handled by
+// stringToNumber before this is consulted): DecimalDigits with an optional
+// fractional part and/or exponent, or a bare ".123"-style fraction. Go's
+// strconv.ParseFloat accepts everything this matches, so a successful match
+// can be handed straight to it.
+var strDecimalLiteralRE = regexp.MustCompile(`^(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+
+// stringToNumber implements the ES StringToNumber abstract operation (the
+// ToNumber(string) case): coerceToNumber's JSString branch. Unlike parsing a
+// number literal out of source code, this never fails — a string that
+// doesn't match the StringNumericLiteral grammar silently becomes NaN, and
+// an empty or whitespace-only string becomes 0.
+func stringToNumber(s string) float64 {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return 0
+	}
+
+	// NonDecimalIntegerLiteral (0x/0o/0b): no sign allowed, unlike the
+	// decimal alternative below.
+	if f, ok := parseNonDecimalIntegerLiteral(trimmed); ok {
+		return f
+	}
+
+	sign := 1.0
+	rest := trimmed
+	switch {
+	case strings.HasPrefix(rest, "+"):
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "-"):
+		sign = -1
+		rest = rest[1:]
+	}
+
+	if rest == "Infinity" {
+		return sign * math.Inf(1)
+	}
+	if !strDecimalLiteralRE.MatchString(rest) {
+		return math.NaN()
+	}
+
+	f, err := strconv.ParseFloat(rest, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return math.NaN()
+	}
+	// on ErrRange f already holds the rounded value (±Inf on overflow)
+	return sign * f
+}
+
+// parseNonDecimalIntegerLiteral recognizes a 0x/0o/0b-prefixed integer
+// literal (no sign, per the StringNumericLiteral grammar) and returns its
+// value. big.Int.SetString does the digit validation, so e.g. "0b12" or a
+// bare "0x" correctly fail rather than silently ignoring the bad digits.
+func parseNonDecimalIntegerLiteral(s string) (float64, bool) {
+	if len(s) < 3 || s[0] != '0' {
+		return 0, false
+	}
+
+	var base int
+	switch s[1] {
+	case 'x', 'X':
+		base = 16
+	case 'o', 'O':
+		base = 8
+	case 'b', 'B':
+		base = 2
+	default:
+		return 0, false
+	}
+
+	i, ok := new(big.Int).SetString(s[2:], base)
+	if !ok {
+		return 0, false
+	}
+	f, _ := new(big.Float).SetInt(i).Float64()
+	return f, true
+}
+
+// numberToString implements Number::toString(n, 10): coerceToString's
+// JSNumber branch. It renders the shortest decimal digit string that
+// round-trips back to n (the same guarantee V8/SpiderMonkey give), then
+// places the decimal point or switches to exponential notation following
+// the spec's threshold (fixed notation for -6 < n <= 21, exponential
+// otherwise, where n is the decimal point's position relative to the first
+// digit).
+func numberToString(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case f == 0:
+		return "0"
+	case f < 0:
+		return "-" + numberToString(-f)
+	case math.IsInf(f, 1):
+		return "Infinity"
+	}
+
+	digits, pointPos := shortestDigits(f)
+	k := len(digits)
+
+	switch {
+	case k <= pointPos && pointPos <= 21:
+		return digits + strings.Repeat("0", pointPos-k)
+	case 0 < pointPos && pointPos <= 21:
+		return digits[:pointPos] + "." + digits[pointPos:]
+	case -6 < pointPos && pointPos <= 0:
+		return "0." + strings.Repeat("0", -pointPos) + digits
+	default:
+		mantissa := digits[:1]
+		if k > 1 {
+			mantissa += "." + digits[1:]
+		}
+		e := pointPos - 1
+		sign := "+"
+		if e < 0 {
+			sign = "-"
+			e = -e
+		}
+		return mantissa + "e" + sign + strconv.Itoa(e)
+	}
+}
+
+// shortestDigits returns the shortest significant-digit string that
+// round-trips to f (f > 0, finite), plus the position of the decimal point
+// relative to those digits: digits interpreted as an integer, times
+// 10^(pointPos-len(digits)), equals f. It leans on Go's own shortest-
+// round-trip formatter (strconv.FormatFloat's 'e' verb with prec -1) rather
+// than reimplementing Ryu/Grisu.
+func shortestDigits(f float64) (digits string, pointPos int) {
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	eIdx := strings.IndexByte(s, 'e')
+	mantissa := strings.Replace(s[:eIdx], ".", "", 1)
+	exp, _ := strconv.Atoi(s[eIdx+1:])
+	return mantissa, exp + 1
+}
+
+// strDecimalIntegerRE matches the ES StrDecimalLiteral grammar restricted
+// to integers (no decimal point, no exponent, no "Infinity"): StringToBigInt
+// rejects anything with a fractional part rather than truncating it, unlike
+// StringToNumber above.
+var strDecimalIntegerRE = regexp.MustCompile(`^[+-]?\d+$`)
+
+// parseStringIntegerLiteral implements the ES StringToBigInt abstract
+// operation: the grammar is almost StringNumericLiteral, but BigInt has no
+// use for a fractional part or an exponent, so both are rejected rather than
+// silently rounded. ok is false exactly when str doesn't denote an integer
+// at all, which callers (BigInt(str), and compareLessThan's string/BigInt
+// branches) treat as "comparison/conversion undefined", not as 0.
+func parseStringIntegerLiteral(str string) (ret *big.Int, ok bool) {
+	trimmed := strings.TrimSpace(str)
+	if trimmed == "" {
+		return big.NewInt(0), true
+	}
+
+	// NonDecimalIntegerLiteral (0x/0o/0b): no sign allowed, unlike the
+	// decimal alternative below. big.Int.SetString does the digit
+	// validation, same as parseNonDecimalIntegerLiteral relies on it for
+	// StringToNumber, just without going through a precision-losing float64.
+	if len(trimmed) >= 3 && trimmed[0] == '0' {
+		var base int
+		switch trimmed[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 0 {
+			return new(big.Int).SetString(trimmed[2:], base)
+		}
+	}
+
+	if !strDecimalIntegerRE.MatchString(trimmed) {
+		return nil, false
+	}
+	return new(big.Int).SetString(trimmed, 10)
+}
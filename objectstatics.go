@@ -0,0 +1,477 @@
+package modeledjs
+
+// toPropertyKeyName converts a JS value used as a property key (as in
+// obj[key], Reflect.get, Object.defineProperty, ...) to the Name that
+// indexes JSObject.descriptors: symbols key by identity, everything else
+// is coerced to a string first.
+func toPropertyKeyName(vm *VM, key JSValue) (Name, error) {
+	if sym, isSym := key.(*JSSymbol); isSym {
+		return SymbolName(sym), nil
+	}
+	s, err := vm.coerceToString(key)
+	if err != nil {
+		return Name{}, err
+	}
+	return NameStr(string(s)), nil
+}
+
+func argOrUndefined(args []JSValue, i int) JSValue {
+	if i < len(args) {
+		return args[i]
+	}
+	return JSUndefined{}
+}
+
+func requireObjectArg(vm *VM, v JSValue, who string) (*JSObject, error) {
+	obj, isObj := v.(*JSObject)
+	if !isObj {
+		return nil, vm.ThrowError("TypeError", who+" called on non-object")
+	}
+	return obj, nil
+}
+
+// descriptorFromPropertyDescriptorObject reads a JS property descriptor
+// object (as passed to Object.defineProperty/Reflect.defineProperty) into a
+// Descriptor, merging unspecified fields with existing (nil if the
+// property doesn't exist yet, in which case unspecified fields default to
+// their ES ToPropertyDescriptor zero value: false/undefined).
+func descriptorFromPropertyDescriptorObject(vm *VM, existing *Descriptor, descObj *JSObject) (Descriptor, error) {
+	var next Descriptor
+	if existing != nil {
+		next = *existing
+	}
+
+	if descObj.HasOwnProperty(NameStr("value")) {
+		v, err := descObj.GetOwnProperty(NameStr("value"), vm)
+		if err != nil {
+			return next, err
+		}
+		next.value = v
+		next.get = nil
+		next.set = nil
+	}
+	if descObj.HasOwnProperty(NameStr("get")) {
+		v, err := descObj.GetOwnProperty(NameStr("get"), vm)
+		if err != nil {
+			return next, err
+		}
+		getFn, err := toAccessorFunctionOrNil(vm, v)
+		if err != nil {
+			return next, err
+		}
+		next.get = getFn
+		next.value = nil
+	}
+	if descObj.HasOwnProperty(NameStr("set")) {
+		v, err := descObj.GetOwnProperty(NameStr("set"), vm)
+		if err != nil {
+			return next, err
+		}
+		setFn, err := toAccessorFunctionOrNil(vm, v)
+		if err != nil {
+			return next, err
+		}
+		next.set = setFn
+		next.value = nil
+	}
+	if descObj.HasOwnProperty(NameStr("writable")) {
+		v, err := descObj.GetOwnProperty(NameStr("writable"), vm)
+		if err != nil {
+			return next, err
+		}
+		next.writable = bool(vm.coerceToBoolean(v))
+	}
+	if descObj.HasOwnProperty(NameStr("enumerable")) {
+		v, err := descObj.GetOwnProperty(NameStr("enumerable"), vm)
+		if err != nil {
+			return next, err
+		}
+		next.enumerable = bool(vm.coerceToBoolean(v))
+	}
+	if descObj.HasOwnProperty(NameStr("configurable")) {
+		v, err := descObj.GetOwnProperty(NameStr("configurable"), vm)
+		if err != nil {
+			return next, err
+		}
+		next.configurable = bool(vm.coerceToBoolean(v))
+	}
+
+	if next.get == nil && next.set == nil && next.value == nil {
+		next.value = JSUndefined{}
+	}
+
+	return next, nil
+}
+
+func toAccessorFunctionOrNil(vm *VM, v JSValue) (*JSObject, error) {
+	if _, isUndef := v.(JSUndefined); isUndef {
+		return nil, nil
+	}
+	fn, isObj := v.(*JSObject)
+	if !isObj || fn.funcPart == nil {
+		return nil, vm.ThrowError("TypeError", "getter/setter must be a function")
+	}
+	return fn, nil
+}
+
+// descriptorToPropertyDescriptorObject is descriptorFromPropertyDescriptorObject's
+// inverse: the plain object Object.getOwnPropertyDescriptor/
+// Reflect.getOwnPropertyDescriptor hand back to JS.
+func descriptorToPropertyDescriptorObject(d *Descriptor) *JSObject {
+	obj := NewJSObject(&ProtoObject)
+	if d.get != nil || d.set != nil {
+		get := JSValue(JSUndefined{})
+		if d.get != nil {
+			get = d.get
+		}
+		set := JSValue(JSUndefined{})
+		if d.set != nil {
+			set = d.set
+		}
+		obj.SetProperty(NameStr("get"), get, nil)
+		obj.SetProperty(NameStr("set"), set, nil)
+	} else {
+		obj.SetProperty(NameStr("value"), d.value, nil)
+		obj.SetProperty(NameStr("writable"), JSBoolean(d.writable), nil)
+	}
+	obj.SetProperty(NameStr("enumerable"), JSBoolean(d.enumerable), nil)
+	obj.SetProperty(NameStr("configurable"), JSBoolean(d.configurable), nil)
+	return &obj
+}
+
+func namesToJSArray(names []Name) *JSObject {
+	arr := NewJSArray()
+	for _, name := range names {
+		var key JSValue
+		if name.isSymbol {
+			key = name.sym
+		} else {
+			key = JSString(name.string)
+		}
+		arr.arrayPart = append(arr.arrayPart, key)
+	}
+	return arr
+}
+
+// installObjectStatics adds the ES2015 reflection methods to the Object
+// constructor: defineProperty, getOwnPropertyDescriptor,
+// getOwnPropertyNames, getOwnPropertySymbols, and the freeze/seal family.
+// They're plain native functions, same as every other global in
+// createGlobalObject; Reflect (see makeReflectObject) exposes the same
+// operations as free functions instead of Object statics.
+func installObjectStatics(consObject *JSObject) {
+	defineProperty := NewNativeFunction(
+		[]string{"target", "key", "descriptor"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Object.defineProperty")
+			if err != nil {
+				return nil, err
+			}
+			name, err := toPropertyKeyName(vm, argOrUndefined(args, 1))
+			if err != nil {
+				return nil, err
+			}
+			descObj, err := requireObjectArg(vm, argOrUndefined(args, 2), "Object.defineProperty")
+			if err != nil {
+				return nil, err
+			}
+
+			existing, _ := target.getOwnPropertyDescriptor(name)
+			next, err := descriptorFromPropertyDescriptorObject(vm, existing, descObj)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := target.DefineProperty(name, next); err != nil {
+				return nil, vm.ThrowError("TypeError", err.Error())
+			}
+			return target, nil
+		})
+	consObject.SetProperty(NameStr("defineProperty"), &defineProperty, nil)
+
+	getOwnPropertyDescriptor := NewNativeFunction(
+		[]string{"target", "key"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Object.getOwnPropertyDescriptor")
+			if err != nil {
+				return nil, err
+			}
+			name, err := toPropertyKeyName(vm, argOrUndefined(args, 1))
+			if err != nil {
+				return nil, err
+			}
+			d, isThere := target.getOwnPropertyDescriptor(name)
+			if !isThere {
+				return JSUndefined{}, nil
+			}
+			return descriptorToPropertyDescriptorObject(d), nil
+		})
+	consObject.SetProperty(NameStr("getOwnPropertyDescriptor"), &getOwnPropertyDescriptor, nil)
+
+	getOwnPropertyNames := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Object.getOwnPropertyNames")
+			if err != nil {
+				return nil, err
+			}
+			return namesToJSArray(target.OwnPropertyNames()), nil
+		})
+	consObject.SetProperty(NameStr("getOwnPropertyNames"), &getOwnPropertyNames, nil)
+
+	getOwnPropertySymbols := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Object.getOwnPropertySymbols")
+			if err != nil {
+				return nil, err
+			}
+			return namesToJSArray(target.OwnPropertySymbols()), nil
+		})
+	consObject.SetProperty(NameStr("getOwnPropertySymbols"), &getOwnPropertySymbols, nil)
+
+	freeze := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			v := argOrUndefined(args, 0)
+			if target, isObj := v.(*JSObject); isObj {
+				target.freeze()
+			}
+			return v, nil
+		})
+	consObject.SetProperty(NameStr("freeze"), &freeze, nil)
+
+	isFrozen := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			v := argOrUndefined(args, 0)
+			target, isObj := v.(*JSObject)
+			if !isObj {
+				// non-objects are vacuously frozen, per spec
+				return JSBoolean(true), nil
+			}
+			return JSBoolean(target.isFrozen()), nil
+		})
+	consObject.SetProperty(NameStr("isFrozen"), &isFrozen, nil)
+
+	seal := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			v := argOrUndefined(args, 0)
+			if target, isObj := v.(*JSObject); isObj {
+				target.seal()
+			}
+			return v, nil
+		})
+	consObject.SetProperty(NameStr("seal"), &seal, nil)
+
+	isSealed := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			v := argOrUndefined(args, 0)
+			target, isObj := v.(*JSObject)
+			if !isObj {
+				// non-objects are vacuously sealed, per spec
+				return JSBoolean(true), nil
+			}
+			return JSBoolean(target.isSealed()), nil
+		})
+	consObject.SetProperty(NameStr("isSealed"), &isSealed, nil)
+
+	preventExtensions := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			v := argOrUndefined(args, 0)
+			if target, isObj := v.(*JSObject); isObj {
+				target.preventExtensions()
+			}
+			return v, nil
+		})
+	consObject.SetProperty(NameStr("preventExtensions"), &preventExtensions, nil)
+
+	isExtensible := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			v := argOrUndefined(args, 0)
+			target, isObj := v.(*JSObject)
+			if !isObj {
+				// non-objects are vacuously non-extensible, per spec
+				return JSBoolean(false), nil
+			}
+			return JSBoolean(target.extensible), nil
+		})
+	consObject.SetProperty(NameStr("isExtensible"), &isExtensible, nil)
+}
+
+// makeReflectObject builds the Reflect global: a plain object (not a
+// constructor) mirroring the same property operations Object's statics
+// expose, plus the ones that have no Object.* equivalent (has,
+// getPrototypeOf).
+func makeReflectObject() *JSObject {
+	reflect := NewJSObject(&ProtoObject)
+
+	get := NewNativeFunction(
+		[]string{"target", "key"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Reflect.get")
+			if err != nil {
+				return nil, err
+			}
+			name, err := toPropertyKeyName(vm, argOrUndefined(args, 1))
+			if err != nil {
+				return nil, err
+			}
+			return target.GetProperty(name, vm)
+		})
+	reflect.SetProperty(NameStr("get"), &get, nil)
+
+	set := NewNativeFunction(
+		[]string{"target", "key", "value"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Reflect.set")
+			if err != nil {
+				return nil, err
+			}
+			name, err := toPropertyKeyName(vm, argOrUndefined(args, 1))
+			if err != nil {
+				return nil, err
+			}
+			if err := target.SetProperty(name, argOrUndefined(args, 2), vm); err != nil {
+				return nil, err
+			}
+			return JSBoolean(true), nil
+		})
+	reflect.SetProperty(NameStr("set"), &set, nil)
+
+	has := NewNativeFunction(
+		[]string{"target", "key"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Reflect.has")
+			if err != nil {
+				return nil, err
+			}
+			name, err := toPropertyKeyName(vm, argOrUndefined(args, 1))
+			if err != nil {
+				return nil, err
+			}
+			for obj := target; obj != nil; obj = obj.Prototype {
+				if obj.HasOwnProperty(name) {
+					return JSBoolean(true), nil
+				}
+			}
+			return JSBoolean(false), nil
+		})
+	reflect.SetProperty(NameStr("has"), &has, nil)
+
+	deleteProperty := NewNativeFunction(
+		[]string{"target", "key"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Reflect.deleteProperty")
+			if err != nil {
+				return nil, err
+			}
+			name, err := toPropertyKeyName(vm, argOrUndefined(args, 1))
+			if err != nil {
+				return nil, err
+			}
+			return JSBoolean(target.DeleteProperty(name)), nil
+		})
+	reflect.SetProperty(NameStr("deleteProperty"), &deleteProperty, nil)
+
+	defineProperty := NewNativeFunction(
+		[]string{"target", "key", "descriptor"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Reflect.defineProperty")
+			if err != nil {
+				return nil, err
+			}
+			name, err := toPropertyKeyName(vm, argOrUndefined(args, 1))
+			if err != nil {
+				return nil, err
+			}
+			descObj, err := requireObjectArg(vm, argOrUndefined(args, 2), "Reflect.defineProperty")
+			if err != nil {
+				return nil, err
+			}
+
+			existing, _ := target.getOwnPropertyDescriptor(name)
+			next, err := descriptorFromPropertyDescriptorObject(vm, existing, descObj)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := target.DefineProperty(name, next); err != nil {
+				return JSBoolean(false), nil
+			}
+			return JSBoolean(true), nil
+		})
+	reflect.SetProperty(NameStr("defineProperty"), &defineProperty, nil)
+
+	getOwnPropertyDescriptor := NewNativeFunction(
+		[]string{"target", "key"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Reflect.getOwnPropertyDescriptor")
+			if err != nil {
+				return nil, err
+			}
+			name, err := toPropertyKeyName(vm, argOrUndefined(args, 1))
+			if err != nil {
+				return nil, err
+			}
+			d, isThere := target.getOwnPropertyDescriptor(name)
+			if !isThere {
+				return JSUndefined{}, nil
+			}
+			return descriptorToPropertyDescriptorObject(d), nil
+		})
+	reflect.SetProperty(NameStr("getOwnPropertyDescriptor"), &getOwnPropertyDescriptor, nil)
+
+	ownKeys := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Reflect.ownKeys")
+			if err != nil {
+				return nil, err
+			}
+			keys := append(target.OwnPropertyNames(), target.OwnPropertySymbols()...)
+			return namesToJSArray(keys), nil
+		})
+	reflect.SetProperty(NameStr("ownKeys"), &ownKeys, nil)
+
+	getPrototypeOf := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Reflect.getPrototypeOf")
+			if err != nil {
+				return nil, err
+			}
+			if target.Prototype == nil {
+				return JSNull{}, nil
+			}
+			return target.Prototype, nil
+		})
+	reflect.SetProperty(NameStr("getPrototypeOf"), &getPrototypeOf, nil)
+
+	isExtensible := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Reflect.isExtensible")
+			if err != nil {
+				return nil, err
+			}
+			return JSBoolean(target.extensible), nil
+		})
+	reflect.SetProperty(NameStr("isExtensible"), &isExtensible, nil)
+
+	preventExtensions := NewNativeFunction(
+		[]string{"target"},
+		func(vm *VM, subject JSValue, args []JSValue, flags CallFlags) (JSValue, error) {
+			target, err := requireObjectArg(vm, argOrUndefined(args, 0), "Reflect.preventExtensions")
+			if err != nil {
+				return nil, err
+			}
+			target.preventExtensions()
+			return JSBoolean(true), nil
+		})
+	reflect.SetProperty(NameStr("preventExtensions"), &preventExtensions, nil)
+
+	return &reflect
+}
@@ -0,0 +1,566 @@
+package modeledjs
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/token"
+)
+
+// optimizer performs a best-effort constant-folding and dead-branch-
+// elimination pass over a parsed ast.Program, enabled per-VM via
+// VMOptions.EnableConstantFolding. Every fold below is justified by the
+// operator's own JS semantics, computed by reusing the same helpers (and,
+// for binary operators, the very same Interpreter.binaryOp) the real
+// execution paths call — so the optimizer's notion of e.g. `1 + "2"` can
+// never drift from what running the program would actually produce.
+//
+// Folding across an identifier reference is only ever done when that
+// identifier is assigned exactly once in its function/program scope, via
+// `var x = <literal>`: a conservative proxy for const-ness, since this
+// subset's grammar doesn't distinguish let/const from var (see DeclKind's
+// doc comment). A use that lexically precedes its declaration never sees
+// the substitution, since constEnv is only extended once a declaration is
+// actually reached while walking its statement list in order.
+type optimizer struct {
+	// vm supplies the coercion/comparison helpers the folder reuses.
+	// It's never asked to run any JS: every value fed to it here comes
+	// straight off a literal AST node, so none of its side-effecting
+	// machinery (property lookups, exceptions reaching user code, ...)
+	// is exercised.
+	vm *VM
+}
+
+// constEnv maps an identifier name to the literal expression it was
+// singly-assigned from, for the innermost function/program scope
+// currently being rewritten.
+type constEnv map[string]ast.Expression
+
+// optimizeProgram folds constant expressions and dead branches in program
+// in place, if vm was constructed with VMOptions.EnableConstantFolding.
+func (vm *VM) optimizeProgram(program *ast.Program) {
+	if !vm.constantFoldingEnabled {
+		return
+	}
+	opt := &optimizer{vm: vm}
+	program.Body = opt.optimizeScope(program.Body)
+}
+
+// optimizeScope rewrites one function/program scope's statement list:
+// list is everything that scope's `var`s hoist through, so assignment
+// counts are gathered across all of it (including inside nested blocks
+// and conditionals) before any substitution is attempted.
+func (opt *optimizer) optimizeScope(list []ast.Statement) []ast.Statement {
+	assignCounts := map[string]int{}
+	countAssignments(list, assignCounts)
+	return opt.rewriteStmtList(list, constEnv{}, assignCounts)
+}
+
+// countAssignments walks list (not descending into nested function
+// bodies, which hoist through their own scope instead) and increments
+// assignCounts for every identifier a `var` declaration, a plain
+// assignment, or ++/-- writes to.
+func countAssignments(list []ast.Statement, assignCounts map[string]int) {
+	for _, stmt := range list {
+		countAssignmentsStmt(stmt, assignCounts)
+	}
+}
+
+func countAssignmentsStmt(stmt ast.Statement, assignCounts map[string]int) {
+	switch s := stmt.(type) {
+	case nil, *ast.EmptyStatement, *ast.BadStatement, *ast.DebuggerStatement, *ast.BranchStatement:
+	case *ast.BlockStatement:
+		countAssignments(s.List, assignCounts)
+	case *ast.ExpressionStatement:
+		countAssignmentsExpr(s.Expression, assignCounts)
+	case *ast.VariableStatement:
+		for _, item := range s.List {
+			if ve, ok := item.(*ast.VariableExpression); ok {
+				if ve.Initializer != nil {
+					assignCounts[ve.Name]++
+					countAssignmentsExpr(ve.Initializer, assignCounts)
+				}
+			}
+		}
+	case *ast.ReturnStatement:
+		countAssignmentsExpr(s.Argument, assignCounts)
+	case *ast.ThrowStatement:
+		countAssignmentsExpr(s.Argument, assignCounts)
+	case *ast.IfStatement:
+		countAssignmentsExpr(s.Test, assignCounts)
+		countAssignmentsStmt(s.Consequent, assignCounts)
+		countAssignmentsStmt(s.Alternate, assignCounts)
+	case *ast.WhileStatement:
+		countAssignmentsExpr(s.Test, assignCounts)
+		countAssignmentsStmt(s.Body, assignCounts)
+	case *ast.DoWhileStatement:
+		countAssignmentsExpr(s.Test, assignCounts)
+		countAssignmentsStmt(s.Body, assignCounts)
+	case *ast.ForStatement:
+		countAssignmentsExpr(s.Initializer, assignCounts)
+		countAssignmentsExpr(s.Test, assignCounts)
+		countAssignmentsExpr(s.Update, assignCounts)
+		countAssignmentsStmt(s.Body, assignCounts)
+	case *ast.ForInStatement:
+		// Into is assigned the current key on every iteration, even
+		// when it's a bare `var x` with no Initializer (so the
+		// VariableExpression case below wouldn't otherwise count it).
+		if ve, ok := s.Into.(*ast.VariableExpression); ok {
+			assignCounts[ve.Name]++
+		} else if id, ok := s.Into.(*ast.Identifier); ok {
+			assignCounts[id.Name]++
+		}
+		countAssignmentsExpr(s.Source, assignCounts)
+		countAssignmentsStmt(s.Body, assignCounts)
+	case *ast.LabelledStatement:
+		countAssignmentsStmt(s.Statement, assignCounts)
+	case *ast.WithStatement:
+		countAssignmentsExpr(s.Object, assignCounts)
+		countAssignmentsStmt(s.Body, assignCounts)
+	case *ast.TryStatement:
+		countAssignmentsStmt(s.Body, assignCounts)
+		if s.Catch != nil {
+			countAssignmentsStmt(s.Catch.Body, assignCounts)
+		}
+		countAssignmentsStmt(s.Finally, assignCounts)
+	case *ast.SwitchStatement:
+		countAssignmentsExpr(s.Discriminant, assignCounts)
+		for _, c := range s.Body {
+			countAssignmentsExpr(c.Test, assignCounts)
+			countAssignments(c.Consequent, assignCounts)
+		}
+	case *ast.FunctionStatement:
+		// A function declaration hoists its own scope; var writes
+		// inside its body belong to that scope, not this one.
+	}
+}
+
+func countAssignmentsExpr(expr ast.Expression, assignCounts map[string]int) {
+	switch e := expr.(type) {
+	case nil:
+	case *ast.AssignExpression:
+		if id, ok := e.Left.(*ast.Identifier); ok {
+			assignCounts[id.Name]++
+		}
+		countAssignmentsExpr(e.Left, assignCounts)
+		countAssignmentsExpr(e.Right, assignCounts)
+	case *ast.UnaryExpression:
+		if e.Operator == token.INCREMENT || e.Operator == token.DECREMENT {
+			if id, ok := e.Operand.(*ast.Identifier); ok {
+				assignCounts[id.Name]++
+			}
+		}
+		countAssignmentsExpr(e.Operand, assignCounts)
+	case *ast.BinaryExpression:
+		countAssignmentsExpr(e.Left, assignCounts)
+		countAssignmentsExpr(e.Right, assignCounts)
+	case *ast.ConditionalExpression:
+		countAssignmentsExpr(e.Test, assignCounts)
+		countAssignmentsExpr(e.Consequent, assignCounts)
+		countAssignmentsExpr(e.Alternate, assignCounts)
+	case *ast.CallExpression:
+		countAssignmentsExpr(e.Callee, assignCounts)
+		for _, a := range e.ArgumentList {
+			countAssignmentsExpr(a, assignCounts)
+		}
+	case *ast.NewExpression:
+		countAssignmentsExpr(e.Callee, assignCounts)
+		for _, a := range e.ArgumentList {
+			countAssignmentsExpr(a, assignCounts)
+		}
+	case *ast.DotExpression:
+		countAssignmentsExpr(e.Left, assignCounts)
+	case *ast.BracketExpression:
+		countAssignmentsExpr(e.Left, assignCounts)
+		countAssignmentsExpr(e.Member, assignCounts)
+	case *ast.ArrayLiteral:
+		for _, v := range e.Value {
+			countAssignmentsExpr(v, assignCounts)
+		}
+	case *ast.ObjectLiteral:
+		for _, p := range e.Value {
+			countAssignmentsExpr(p.Value, assignCounts)
+		}
+	case *ast.SequenceExpression:
+		for _, v := range e.Sequence {
+			countAssignmentsExpr(v, assignCounts)
+		}
+	case *ast.VariableExpression:
+		if e.Initializer != nil {
+			assignCounts[e.Name]++
+			countAssignmentsExpr(e.Initializer, assignCounts)
+		}
+	case *ast.FunctionLiteral:
+		// Its body hoists through its own scope, not this one.
+	}
+}
+
+// rewriteStmtList rewrites list statement by statement, extending env
+// with each single-assignment `var x = <literal>` as its declaration is
+// reached so later siblings (and anything nested under them) may use it,
+// while earlier ones never do.
+func (opt *optimizer) rewriteStmtList(list []ast.Statement, env constEnv, assignCounts map[string]int) []ast.Statement {
+	out := make([]ast.Statement, len(list))
+	for i, stmt := range list {
+		out[i] = opt.rewriteStmt(stmt, env, assignCounts)
+
+		vs, ok := stmt.(*ast.VariableStatement)
+		if !ok {
+			continue
+		}
+		for _, item := range vs.List {
+			ve, ok := item.(*ast.VariableExpression)
+			if !ok || ve.Initializer == nil || assignCounts[ve.Name] != 1 {
+				continue
+			}
+			if _, isLit := literalToJSValue(ve.Initializer); isLit {
+				env[ve.Name] = ve.Initializer
+			}
+		}
+	}
+	return out
+}
+
+func (opt *optimizer) rewriteStmt(stmt ast.Statement, env constEnv, assignCounts map[string]int) ast.Statement {
+	switch s := stmt.(type) {
+	case nil:
+		return nil
+
+	case *ast.BlockStatement:
+		s.List = opt.rewriteStmtList(s.List, env, assignCounts)
+		return s
+
+	case *ast.ExpressionStatement:
+		s.Expression = opt.rewriteExpr(s.Expression, env)
+		return s
+
+	case *ast.VariableStatement:
+		for _, item := range s.List {
+			if ve, ok := item.(*ast.VariableExpression); ok && ve.Initializer != nil {
+				ve.Initializer = opt.rewriteExpr(ve.Initializer, env)
+			}
+		}
+		return s
+
+	case *ast.ReturnStatement:
+		if s.Argument != nil {
+			s.Argument = opt.rewriteExpr(s.Argument, env)
+		}
+		return s
+
+	case *ast.ThrowStatement:
+		s.Argument = opt.rewriteExpr(s.Argument, env)
+		return s
+
+	case *ast.IfStatement:
+		s.Test = opt.rewriteExpr(s.Test, env)
+		if v, ok := opt.literalValue(s.Test, env); ok {
+			if opt.vm.coerceToBoolean(v) {
+				return opt.rewriteBranch(s.Consequent, env, assignCounts)
+			}
+			return opt.rewriteBranch(s.Alternate, env, assignCounts)
+		}
+		s.Consequent = opt.rewriteStmt(s.Consequent, env, assignCounts)
+		if s.Alternate != nil {
+			s.Alternate = opt.rewriteStmt(s.Alternate, env, assignCounts)
+		}
+		return s
+
+	case *ast.WhileStatement:
+		s.Test = opt.rewriteExpr(s.Test, env)
+		if v, ok := opt.literalValue(s.Test, env); ok && !bool(opt.vm.coerceToBoolean(v)) {
+			return &ast.EmptyStatement{Semicolon: s.Idx0()}
+		}
+		s.Body = opt.rewriteStmt(s.Body, env, assignCounts)
+		return s
+
+	case *ast.DoWhileStatement:
+		s.Test = opt.rewriteExpr(s.Test, env)
+		s.Body = opt.rewriteStmt(s.Body, env, assignCounts)
+		return s
+
+	case *ast.ForStatement:
+		s.Initializer = opt.rewriteExpr(s.Initializer, env)
+		s.Test = opt.rewriteExpr(s.Test, env)
+		s.Update = opt.rewriteExpr(s.Update, env)
+		s.Body = opt.rewriteStmt(s.Body, env, assignCounts)
+		return s
+
+	case *ast.ForInStatement:
+		s.Source = opt.rewriteExpr(s.Source, env)
+		s.Body = opt.rewriteStmt(s.Body, env, assignCounts)
+		return s
+
+	case *ast.LabelledStatement:
+		s.Statement = opt.rewriteStmt(s.Statement, env, assignCounts)
+		return s
+
+	case *ast.WithStatement:
+		s.Object = opt.rewriteExpr(s.Object, env)
+		s.Body = opt.rewriteStmt(s.Body, env, assignCounts)
+		return s
+
+	case *ast.TryStatement:
+		s.Body = opt.rewriteStmt(s.Body, env, assignCounts)
+		if s.Catch != nil {
+			s.Catch.Body = opt.rewriteStmt(s.Catch.Body, env, assignCounts)
+		}
+		if s.Finally != nil {
+			s.Finally = opt.rewriteStmt(s.Finally, env, assignCounts)
+		}
+		return s
+
+	case *ast.SwitchStatement:
+		s.Discriminant = opt.rewriteExpr(s.Discriminant, env)
+		for _, c := range s.Body {
+			c.Test = opt.rewriteExpr(c.Test, env)
+			c.Consequent = opt.rewriteStmtList(c.Consequent, env, assignCounts)
+		}
+		return s
+
+	case *ast.FunctionStatement:
+		s.Function.Body = opt.optimizeFunctionBody(s.Function.Body)
+		return s
+
+	default:
+		return s
+	}
+}
+
+// rewriteBranch optimizes the surviving side of an eliminated
+// IfStatement, returning an EmptyStatement in place of a missing/nil
+// branch so the caller never needs to special-case "if had no else".
+func (opt *optimizer) rewriteBranch(branch ast.Statement, env constEnv, assignCounts map[string]int) ast.Statement {
+	if branch == nil {
+		return &ast.EmptyStatement{}
+	}
+	return opt.rewriteStmt(branch, env, assignCounts)
+}
+
+// optimizeFunctionBody rewrites a function literal's body as its own
+// scope: it doesn't inherit the enclosing scope's constEnv, since a
+// closure can run after an outer single-assignment local has gone out of
+// reach of our straight-line reasoning (e.g. captured before a later
+// reassignment that our single-scope analysis already refuses to allow,
+// but which could exist in an enclosing scope this pass never looked at).
+func (opt *optimizer) optimizeFunctionBody(body ast.Statement) ast.Statement {
+	block, ok := body.(*ast.BlockStatement)
+	if !ok {
+		return body
+	}
+	block.List = opt.optimizeScope(block.List)
+	return block
+}
+
+func (opt *optimizer) rewriteExpr(expr ast.Expression, env constEnv) ast.Expression {
+	switch e := expr.(type) {
+	case nil:
+		return nil
+
+	case *ast.BinaryExpression:
+		e.Left = opt.rewriteExpr(e.Left, env)
+		e.Right = opt.rewriteExpr(e.Right, env)
+		lv, lok := opt.literalValue(e.Left, env)
+		rv, rok := opt.literalValue(e.Right, env)
+		if lok && rok {
+			interp := &Interpreter{vm: opt.vm}
+			if result, err := interp.binaryOp(e.Operator, lv, rv); err == nil {
+				if lit, ok := jsValueToLiteral(result); ok {
+					return lit
+				}
+			}
+		}
+		return e
+
+	case *ast.UnaryExpression:
+		e.Operand = opt.rewriteExpr(e.Operand, env)
+		return opt.foldUnary(e, env)
+
+	case *ast.ConditionalExpression:
+		e.Test = opt.rewriteExpr(e.Test, env)
+		e.Consequent = opt.rewriteExpr(e.Consequent, env)
+		e.Alternate = opt.rewriteExpr(e.Alternate, env)
+		return e
+
+	case *ast.AssignExpression:
+		e.Right = opt.rewriteExpr(e.Right, env)
+		return e
+
+	case *ast.CallExpression:
+		e.Callee = opt.rewriteExpr(e.Callee, env)
+		for i, a := range e.ArgumentList {
+			e.ArgumentList[i] = opt.rewriteExpr(a, env)
+		}
+		return e
+
+	case *ast.NewExpression:
+		e.Callee = opt.rewriteExpr(e.Callee, env)
+		for i, a := range e.ArgumentList {
+			e.ArgumentList[i] = opt.rewriteExpr(a, env)
+		}
+		return e
+
+	case *ast.DotExpression:
+		e.Left = opt.rewriteExpr(e.Left, env)
+		return e
+
+	case *ast.BracketExpression:
+		e.Left = opt.rewriteExpr(e.Left, env)
+		e.Member = opt.rewriteExpr(e.Member, env)
+		return e
+
+	case *ast.ArrayLiteral:
+		for i, v := range e.Value {
+			e.Value[i] = opt.rewriteExpr(v, env)
+		}
+		return e
+
+	case *ast.ObjectLiteral:
+		for i, p := range e.Value {
+			e.Value[i].Value = opt.rewriteExpr(p.Value, env)
+		}
+		return e
+
+	case *ast.SequenceExpression:
+		for i, v := range e.Sequence {
+			e.Sequence[i] = opt.rewriteExpr(v, env)
+		}
+		return e
+
+	case *ast.VariableExpression:
+		if e.Initializer != nil {
+			e.Initializer = opt.rewriteExpr(e.Initializer, env)
+		}
+		return e
+
+	case *ast.FunctionLiteral:
+		e.Body = opt.optimizeFunctionBody(e.Body)
+		return e
+
+	default:
+		return e
+	}
+}
+
+// foldUnary resolves a unary operator applied to an (already rewritten)
+// literal or const-bound operand. Operators with a side effect on their
+// operand (++, --, delete) are never folded.
+func (opt *optimizer) foldUnary(e *ast.UnaryExpression, env constEnv) ast.Expression {
+	switch e.Operator {
+	case token.NOT, token.TYPEOF, token.MINUS, token.PLUS:
+	default:
+		return e
+	}
+
+	v, ok := opt.literalValue(e.Operand, env)
+	if !ok {
+		return e
+	}
+
+	switch e.Operator {
+	case token.NOT:
+		if lit, ok := jsValueToLiteral(!opt.vm.coerceToBoolean(v)); ok {
+			return lit
+		}
+
+	case token.TYPEOF:
+		if lit, ok := jsValueToLiteral(typeofString(v)); ok {
+			return lit
+		}
+
+	case token.MINUS:
+		if num, err := opt.vm.coerceNumeric(v); err == nil {
+			switch n := num.(type) {
+			case JSNumber:
+				if lit, ok := jsValueToLiteral(JSNumber(-n)); ok {
+					return lit
+				}
+			case JSBigInt:
+				if lit, ok := jsValueToLiteral(NewBigInt(new(big.Int).Neg(n.v))); ok {
+					return lit
+				}
+			}
+		}
+
+	case token.PLUS:
+		if num, err := opt.vm.coerceNumeric(v); err == nil {
+			if lit, ok := jsValueToLiteral(num); ok {
+				return lit
+			}
+		}
+	}
+
+	return e
+}
+
+// literalValue resolves expr to the JSValue it statically denotes: expr
+// itself if it's already a literal node, or env's recorded initializer if
+// it's an identifier bound to one.
+func (opt *optimizer) literalValue(expr ast.Expression, env constEnv) (JSValue, bool) {
+	if id, isIdent := expr.(*ast.Identifier); isIdent {
+		lit, ok := env[id.Name]
+		if !ok {
+			return nil, false
+		}
+		expr = lit
+	}
+	return literalToJSValue(expr)
+}
+
+// literalToJSValue converts a literal AST node to the JSValue it denotes.
+func literalToJSValue(expr ast.Expression) (JSValue, bool) {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		// See the matching comment in evalExpr: an int64 Value just means
+		// otto's parser could represent the literal exactly as an integer,
+		// not that it's a BigInt — only a Literal ending in "n" means that.
+		if strings.HasSuffix(e.Literal, "n") {
+			if v, ok := e.Value.(int64); ok {
+				return bigIntFromInt64(v), true
+			}
+			return nil, false
+		}
+		switch v := e.Value.(type) {
+		case float64:
+			return JSNumber(v), true
+		case int64:
+			return JSNumber(v), true
+		}
+		return nil, false
+	case *ast.StringLiteral:
+		return JSString(e.Value), true
+	case *ast.BooleanLiteral:
+		return JSBoolean(e.Value), true
+	case *ast.NullLiteral:
+		return JSNull{}, true
+	default:
+		return nil, false
+	}
+}
+
+// jsValueToLiteral converts a JSValue produced by folding back into the
+// literal AST node the compiler already knows how to emit.
+func jsValueToLiteral(v JSValue) (ast.Expression, bool) {
+	switch v := v.(type) {
+	case JSNumber:
+		return &ast.NumberLiteral{Value: float64(v), Literal: numberToString(float64(v))}, true
+	case JSString:
+		return &ast.StringLiteral{Value: string(v), Literal: string(v)}, true
+	case JSBoolean:
+		literal := "false"
+		if v {
+			literal = "true"
+		}
+		return &ast.BooleanLiteral{Value: bool(v), Literal: literal}, true
+	case JSNull:
+		return &ast.NullLiteral{Literal: "null"}, true
+	case JSBigInt:
+		if v.v.IsInt64() {
+			return &ast.NumberLiteral{Value: v.v.Int64(), Literal: v.v.String() + "n"}, true
+		}
+		return nil, false
+	default:
+		return nil, false
+	}
+}
@@ -3,58 +3,55 @@ package modeledjs
 import (
 	"fmt"
 	"io"
-	"reflect"
 	"strings"
 
-	"github.com/robertkrimen/otto/ast"
-	parserFile "github.com/robertkrimen/otto/file"
-	"github.com/robertkrimen/otto/parser"
+	ts "github.com/smacker/go-tree-sitter"
+
+	tsparser "com.github.sebastianobarrera.modeledjs/modeledjs/ts-parser"
 )
 
+// PrintAST parses rdr as JavaScript with tree-sitter and prints an indented
+// dump of the resulting parse tree to stdout. This used to go through
+// otto's parser, which bails out on modern syntax tree-sitter handles fine;
+// tree-sitter is now the only parser this function depends on.
 func PrintAST(rdr io.Reader) (err error) {
-	program, err := parser.ParseFile(nil, "<>", rdr, 0)
+	src, err := io.ReadAll(rdr)
+	if err != nil {
+		return err
+	}
 
-	walker := &printer{
-		file: program.File,
+	tree, diags, err := tsparser.Parse("<>", src)
+	if err != nil {
+		return err
 	}
-	ast.Walk(walker, program)
 
-	return
-}
+	printNode(tree, tree.Root, 0)
+
+	for _, d := range diags {
+		fmt.Println(d.String())
+	}
 
-type printer struct {
-	file   *parserFile.File
-	indent int
+	return nil
 }
 
-func (p *printer) Enter(n ast.Node) (v ast.Visitor) {
-	for i := 0; i < p.indent; i++ {
+func printNode(tree *tsparser.Tree, node *ts.Node, indent int) {
+	for i := 0; i < indent; i++ {
 		fmt.Print("|   ")
 	}
-	t := reflect.TypeOf(n)
-
-	start := n.Idx0() - 1
-	end := n.Idx1() - 1
-	subSrc := ""
-	src := p.file.Source()
-	if int(end) < len(src) {
-		subSrc = src[start:end]
-	}
 
-	if strings.Contains(subSrc, "\n") {
-		subSrc = ""
+	start := node.StartByte()
+	end := node.EndByte()
+	snippet := ""
+	if int(end) <= len(tree.Source) {
+		snippet = string(tree.Source[start:end])
 	}
-
-	if pos := p.file.Position(n.Idx0()); pos != nil {
-		fmt.Printf("%s:  %s  `%s`\n", t.String(), pos, subSrc)
-	} else {
-		fmt.Printf("%s:  %s  `%s`\n", t.String(), pos, subSrc)
+	if strings.Contains(snippet, "\n") {
+		snippet = ""
 	}
 
-	p.indent++
-	return p
-}
+	fmt.Printf("%s:  %d:%d  `%s`\n", node.Type(), node.StartPoint().Row+1, node.StartPoint().Column+1, snippet)
 
-func (p *printer) Exit(n ast.Node) {
-	p.indent--
+	for i := 0; i < int(node.ChildCount()); i++ {
+		printNode(tree, node.Child(i), indent+1)
+	}
 }
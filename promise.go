@@ -0,0 +1,169 @@
+package modeledjs
+
+import "errors"
+
+// PromiseState is a JSObject's promisePart: it holds the pending/settled
+// status and the queue of reactions registered by then/catch before
+// settlement. It gives OpAwait (reserved in compiler.go, not emitted
+// yet) something to suspend against once compileExpr gains an
+// AwaitExpression case.
+type PromiseState struct {
+	status PromiseStatus
+	value  JSValue // the fulfillment value or rejection reason, once settled
+
+	// reactions queues onFulfilled/onRejected pairs registered while the
+	// promise was still pending; each is run as a microtask once the
+	// promise settles.
+	reactions []promiseReaction
+}
+
+type PromiseStatus uint8
+
+const (
+	PromisePending PromiseStatus = iota
+	PromiseFulfilled
+	PromiseRejected
+)
+
+type promiseReaction struct {
+	onFulfilled *JSObject
+	onRejected  *JSObject
+	result      *JSObject // the promise `then` returned, to settle in turn
+}
+
+var ProtoPromise = NewJSObject(&ProtoObject)
+
+func init() {
+	then := NewNativeFunction([]string{"onFulfilled", "onRejected"}, func(vm *VM, subject JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		self, err := asPromise(subject)
+		if err != nil {
+			return nil, vm.ThrowError("TypeError", err.Error())
+		}
+		var onFulfilled, onRejected *JSObject
+		if len(args) > 0 {
+			onFulfilled, _ = args[0].(*JSObject)
+		}
+		if len(args) > 1 {
+			onRejected, _ = args[1].(*JSObject)
+		}
+		return vm.promiseThen(self, onFulfilled, onRejected), nil
+	})
+	ProtoPromise.SetProperty(NameStr("then"), &then, nil)
+
+	catch := NewNativeFunction([]string{"onRejected"}, func(vm *VM, subject JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		self, err := asPromise(subject)
+		if err != nil {
+			return nil, vm.ThrowError("TypeError", err.Error())
+		}
+		var onRejected *JSObject
+		if len(args) > 0 {
+			onRejected, _ = args[0].(*JSObject)
+		}
+		return vm.promiseThen(self, nil, onRejected), nil
+	})
+	ProtoPromise.SetProperty(NameStr("catch"), &catch, nil)
+}
+
+func asPromise(subject JSValue) (*JSObject, error) {
+	obj, isObj := subject.(*JSObject)
+	if !isObj || obj.promisePart == nil {
+		return nil, ErrNotAPromise
+	}
+	return obj, nil
+}
+
+// ErrNotAPromise is returned (wrapped into a TypeError) when then/catch is
+// called on a receiver that isn't a promise.
+var ErrNotAPromise = errors.New("not a promise")
+
+// NewPromise creates a pending JSPromise-shaped object.
+func NewPromise() *JSObject {
+	obj := NewJSObject(&ProtoPromise)
+	obj.promisePart = &PromiseState{status: PromisePending}
+	return &obj
+}
+
+// ResolvePromise settles p as fulfilled with value, scheduling any
+// already-registered reactions as microtasks. Settling an already-settled
+// promise is a no-op, matching the spec.
+func (vm *VM) ResolvePromise(p *JSObject, value JSValue) {
+	vm.settlePromise(p, PromiseFulfilled, value)
+}
+
+// RejectPromise settles p as rejected with reason.
+func (vm *VM) RejectPromise(p *JSObject, reason JSValue) {
+	vm.settlePromise(p, PromiseRejected, reason)
+}
+
+func (vm *VM) settlePromise(p *JSObject, status PromiseStatus, value JSValue) {
+	ps := p.promisePart
+	if ps.status != PromisePending {
+		return
+	}
+	ps.status = status
+	ps.value = value
+
+	reactions := ps.reactions
+	ps.reactions = nil
+	for _, r := range reactions {
+		vm.scheduleReaction(r, status, value)
+	}
+}
+
+// promiseThen implements the shared logic behind .then and .catch: returns
+// a new promise that settles once onFulfilled/onRejected (whichever
+// applies) has run against self's outcome.
+func (vm *VM) promiseThen(self *JSObject, onFulfilled, onRejected *JSObject) *JSObject {
+	result := NewPromise()
+	reaction := promiseReaction{onFulfilled: onFulfilled, onRejected: onRejected, result: result}
+
+	ps := self.promisePart
+	if ps.status == PromisePending {
+		ps.reactions = append(ps.reactions, reaction)
+	} else {
+		vm.scheduleReaction(reaction, ps.status, ps.value)
+	}
+	return result
+}
+
+func (vm *VM) scheduleReaction(r promiseReaction, status PromiseStatus, value JSValue) {
+	vm.microtasks = append(vm.microtasks, func() {
+		var handler *JSObject
+		if status == PromiseFulfilled {
+			handler = r.onFulfilled
+		} else {
+			handler = r.onRejected
+		}
+
+		if handler == nil {
+			// propagate the outcome unchanged, as `then` with a missing
+			// handler does
+			vm.settlePromise(r.result, status, value)
+			return
+		}
+
+		ret, err := handler.Invoke(vm, JSUndefined{}, []JSValue{value}, CallFlags{})
+		if err != nil {
+			if pexc, isExc := err.(ProgramException); isExc {
+				vm.RejectPromise(r.result, pexc.exceptionValue)
+				return
+			}
+			vm.RejectPromise(r.result, JSString(err.Error()))
+			return
+		}
+		vm.ResolvePromise(r.result, ret)
+	})
+}
+
+// RunMicrotasks drains the promise-reaction queue, including any further
+// reactions those reactions themselves schedule, until it's empty.
+// RunScriptReader calls this automatically after running a script's top
+// level; embedders driving generator/async code directly should call it
+// whenever they want pending reactions to actually run.
+func (vm *VM) RunMicrotasks() {
+	for len(vm.microtasks) > 0 {
+		task := vm.microtasks[0]
+		vm.microtasks = vm.microtasks[1:]
+		task()
+	}
+}
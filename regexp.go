@@ -0,0 +1,747 @@
+package modeledjs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RegexpPart is a JSObject's regexpPart: the source text and flags a
+// RegExp literal or `new RegExp(...)` call was built from, plus the
+// CompiledRegexp the configured RegexpEngine produced for them. "lastIndex"
+// and the other spec-visible properties (source, flags, global, ...) live
+// as ordinary own properties, set once in newRegExp, the same way
+// ThrowError stamps "name"/"message" onto the objects it throws.
+type RegexpPart struct {
+	pattern  string
+	flags    RegexpFlags
+	compiled CompiledRegexp
+}
+
+// RegexpFlags are the bit flags a JS regex literal or RegExp(pattern,
+// flags) call can set.
+type RegexpFlags uint8
+
+const (
+	RegexpGlobal RegexpFlags = 1 << iota
+	RegexpIgnoreCase
+	RegexpMultiline
+	RegexpDotAll
+	RegexpUnicode
+	RegexpSticky
+)
+
+// String renders flags in the canonical order RegExp.prototype.flags
+// requires: "gimsuy" filtered down to whichever are set.
+func (flags RegexpFlags) String() string {
+	var sb strings.Builder
+	for _, entry := range [...]struct {
+		bit RegexpFlags
+		ch  byte
+	}{
+		{RegexpGlobal, 'g'},
+		{RegexpIgnoreCase, 'i'},
+		{RegexpMultiline, 'm'},
+		{RegexpDotAll, 's'},
+		{RegexpUnicode, 'u'},
+		{RegexpSticky, 'y'},
+	} {
+		if flags&entry.bit != 0 {
+			sb.WriteByte(entry.ch)
+		}
+	}
+	return sb.String()
+}
+
+// parseRegexpFlags validates a flags string (the text after a literal's
+// closing `/`, or RegExp's second constructor argument) and packs it into
+// RegexpFlags.
+func parseRegexpFlags(s string) (RegexpFlags, error) {
+	var flags RegexpFlags
+	for _, c := range s {
+		var bit RegexpFlags
+		switch c {
+		case 'g':
+			bit = RegexpGlobal
+		case 'i':
+			bit = RegexpIgnoreCase
+		case 'm':
+			bit = RegexpMultiline
+		case 's':
+			bit = RegexpDotAll
+		case 'u':
+			bit = RegexpUnicode
+		case 'y':
+			bit = RegexpSticky
+		default:
+			return 0, fmt.Errorf("invalid regular expression flag: %q", c)
+		}
+		if flags&bit != 0 {
+			return 0, fmt.Errorf("duplicate regular expression flag: %q", c)
+		}
+		flags |= bit
+	}
+	return flags, nil
+}
+
+// CompiledRegexp matches a compiled pattern against a subject string,
+// starting the search at or after a given offset. Offsets are plain byte
+// offsets into the Go string backing a JSString today (see chunk3-6 for
+// proper UTF-16 code-unit semantics, which this predates).
+type CompiledRegexp interface {
+	// FindSubmatchIndex returns index pairs for the whole match and each
+	// capture group, flattened the way
+	// regexp.Regexp.FindStringSubmatchIndex does (so result[2*i:2*i+2] is
+	// group i's [start, end), or [-1,-1] if it didn't participate), or nil
+	// if there's no match at or after start.
+	FindSubmatchIndex(s string, start int) []int
+	// GroupNames returns each capture group's name indexed by group
+	// number (0 is the whole match and has no name), "" for unnamed
+	// groups, mirroring regexp.Regexp.SubexpNames.
+	GroupNames() []string
+}
+
+// RegexpEngine compiles a JS regex pattern into a CompiledRegexp. VM uses
+// goRegexpEngine by default, which covers the subset of JS regex syntax
+// Go's RE2-based regexp package can express. An embedder that needs
+// backreferences, lookaround, or other RE2-incompatible constructs can
+// install a full ECMAScript-compatible engine with SetRegexpEngine.
+type RegexpEngine interface {
+	Compile(pattern string, flags RegexpFlags) (CompiledRegexp, error)
+}
+
+// SetRegexpEngine installs engine as the RegexpEngine every RegExp literal
+// and `new RegExp(...)` call compiles its pattern through, replacing the
+// default goRegexpEngine.
+func (vm *VM) SetRegexpEngine(engine RegexpEngine) {
+	vm.regexpEngine = engine
+}
+
+// goRegexpEngine is the default RegexpEngine: it rewrites the handful of
+// JS regex constructs RE2 spells differently (named groups) and rejects,
+// with a readable error, the constructs RE2 can't express at all
+// (backreferences, lookaround).
+type goRegexpEngine struct{}
+
+func (goRegexpEngine) Compile(pattern string, flags RegexpFlags) (CompiledRegexp, error) {
+	translated, err := translateJSRegexpPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var inline string
+	if flags&RegexpIgnoreCase != 0 {
+		inline += "i"
+	}
+	if flags&RegexpMultiline != 0 {
+		inline += "m"
+	}
+	if flags&RegexpDotAll != 0 {
+		inline += "s"
+	}
+	if inline != "" {
+		translated = "(?" + inline + ")" + translated
+	}
+
+	re, err := regexp.Compile(translated)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported regular expression syntax: %w", err)
+	}
+	return &goCompiledRegexp{re: re}, nil
+}
+
+type goCompiledRegexp struct {
+	re *regexp.Regexp
+}
+
+func (c *goCompiledRegexp) FindSubmatchIndex(s string, start int) []int {
+	if start > len(s) {
+		return nil
+	}
+	idx := c.re.FindStringSubmatchIndex(s[start:])
+	if idx == nil {
+		return nil
+	}
+	for i, v := range idx {
+		if v >= 0 {
+			idx[i] = v + start
+		}
+	}
+	return idx
+}
+
+func (c *goCompiledRegexp) GroupNames() []string {
+	return c.re.SubexpNames()
+}
+
+// translateJSRegexpPattern rewrites JS regex syntax that RE2 spells
+// differently (named groups) and rejects, with a SyntaxError-worthy
+// message instead of a confusing regexp.Compile failure, the constructs
+// RE2 can't express at all (backreferences, lookaround).
+func translateJSRegexpPattern(pattern string) (string, error) {
+	var out strings.Builder
+	inClass := false
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+
+		if c == '\\' && i+1 < len(pattern) {
+			next := pattern[i+1]
+			if !inClass && next >= '1' && next <= '9' {
+				return "", fmt.Errorf("backreferences are not supported by the default regexp engine")
+			}
+			out.WriteByte(c)
+			out.WriteByte(next)
+			i++
+			continue
+		}
+
+		if c == '[' {
+			inClass = true
+		} else if c == ']' {
+			inClass = false
+		}
+
+		if !inClass && c == '(' && i+1 < len(pattern) && pattern[i+1] == '?' {
+			rest := pattern[i+2:]
+			switch {
+			case strings.HasPrefix(rest, "<=") || strings.HasPrefix(rest, "<!"):
+				return "", fmt.Errorf("lookbehind assertions are not supported by the default regexp engine")
+			case strings.HasPrefix(rest, "=") || strings.HasPrefix(rest, "!"):
+				return "", fmt.Errorf("lookahead assertions are not supported by the default regexp engine")
+			case strings.HasPrefix(rest, "<"):
+				// named group: (?<name>...) -> (?P<name>...)
+				out.WriteString("(?P<")
+				i += 2
+				continue
+			}
+		}
+
+		out.WriteByte(c)
+	}
+
+	return out.String(), nil
+}
+
+// newRegExp compiles pattern/flagsStr through vm's RegexpEngine and wraps
+// the result in a RegExp-shaped JSObject, with every spec-visible property
+// (source, flags, global, ..., lastIndex) stamped on as a plain own
+// property, the way ThrowError stamps "name"/"message" onto the objects it
+// throws.
+func (vm *VM) newRegExp(pattern, flagsStr string) (*JSObject, error) {
+	flags, err := parseRegexpFlags(flagsStr)
+	if err != nil {
+		return nil, vm.ThrowError("SyntaxError", err.Error())
+	}
+
+	engine := vm.regexpEngine
+	if engine == nil {
+		engine = goRegexpEngine{}
+	}
+	compiled, err := engine.Compile(pattern, flags)
+	if err != nil {
+		return nil, vm.ThrowError("SyntaxError", err.Error())
+	}
+
+	obj := NewJSObject(&ProtoRegexp)
+	obj.regexpPart = &RegexpPart{pattern: pattern, flags: flags, compiled: compiled}
+
+	props := []struct {
+		name  string
+		value JSValue
+	}{
+		{"source", JSString(pattern)},
+		{"flags", JSString(flags.String())},
+		{"global", JSBoolean(flags&RegexpGlobal != 0)},
+		{"ignoreCase", JSBoolean(flags&RegexpIgnoreCase != 0)},
+		{"multiline", JSBoolean(flags&RegexpMultiline != 0)},
+		{"dotAll", JSBoolean(flags&RegexpDotAll != 0)},
+		{"unicode", JSBoolean(flags&RegexpUnicode != 0)},
+		{"sticky", JSBoolean(flags&RegexpSticky != 0)},
+		{"lastIndex", JSNumber(0)},
+	}
+	for _, p := range props {
+		if err := obj.SetProperty(NameStr(p.name), p.value, vm); err != nil {
+			panic("SetProperty must not fail here!")
+		}
+	}
+
+	return &obj, nil
+}
+
+func asRegExp(vm *VM, subject JSValue) (*JSObject, error) {
+	obj, isObj := subject.(*JSObject)
+	if !isObj || obj.regexpPart == nil {
+		return nil, vm.ThrowError("TypeError", "Method called on an incompatible receiver: not a RegExp")
+	}
+	return obj, nil
+}
+
+func init() {
+	test := NewNativeFunction([]string{"str"}, func(vm *VM, subject JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		re, err := asRegExp(vm, subject)
+		if err != nil {
+			return nil, err
+		}
+		str, err := vm.coerceToString(argOrUndefined(args, 0))
+		if err != nil {
+			return nil, err
+		}
+		match, err := vm.execRegexp(re, string(str))
+		if err != nil {
+			return nil, err
+		}
+		_, isNull := match.(JSNull)
+		return JSBoolean(!isNull), nil
+	})
+	ProtoRegexp.SetProperty(NameStr("test"), &test, nil)
+
+	exec := NewNativeFunction([]string{"str"}, func(vm *VM, subject JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		re, err := asRegExp(vm, subject)
+		if err != nil {
+			return nil, err
+		}
+		str, err := vm.coerceToString(argOrUndefined(args, 0))
+		if err != nil {
+			return nil, err
+		}
+		return vm.execRegexp(re, string(str))
+	})
+	ProtoRegexp.SetProperty(NameStr("exec"), &exec, nil)
+
+	toString := NewNativeFunction(nil, func(vm *VM, subject JSValue, _ []JSValue, _ CallFlags) (JSValue, error) {
+		re, err := asRegExp(vm, subject)
+		if err != nil {
+			return nil, err
+		}
+		return JSString(fmt.Sprintf("/%s/%s", re.regexpPart.pattern, re.regexpPart.flags.String())), nil
+	})
+	ProtoRegexp.SetProperty(NameStr("toString"), &toString, nil)
+}
+
+// execRegexp implements RegExp.prototype.exec: runs re against str
+// starting from its "lastIndex" property when re is global or sticky (0
+// otherwise), returns JSNull{} on no match, and otherwise an array of
+// [wholeMatch, group1, ...] with "index", "input" and "groups" own
+// properties — advancing (or, on failure, resetting) "lastIndex" the way
+// the spec requires.
+func (vm *VM) execRegexp(re *JSObject, str string) (JSValue, error) {
+	rp := re.regexpPart
+	tracksLastIndex := rp.flags&(RegexpGlobal|RegexpSticky) != 0
+
+	start := 0
+	if tracksLastIndex {
+		lastIndexVal, err := re.GetProperty(NameStr("lastIndex"), vm)
+		if err != nil {
+			return nil, err
+		}
+		lastIndexNum, err := vm.coerceToNumber(lastIndexVal)
+		if err != nil {
+			return nil, err
+		}
+		if start = int(lastIndexNum); start < 0 {
+			start = 0
+		}
+	}
+
+	noMatch := func() (JSValue, error) {
+		if tracksLastIndex {
+			if err := re.SetProperty(NameStr("lastIndex"), JSNumber(0), vm); err != nil {
+				return nil, err
+			}
+		}
+		return JSNull{}, nil
+	}
+
+	if start > len(str) {
+		return noMatch()
+	}
+
+	idx := rp.compiled.FindSubmatchIndex(str, start)
+	if idx == nil || (rp.flags&RegexpSticky != 0 && idx[0] != start) {
+		return noMatch()
+	}
+
+	if tracksLastIndex {
+		newLastIndex := idx[1]
+		if idx[0] == idx[1] {
+			// a zero-width match at the current position would otherwise
+			// make exec/replace/split loop forever
+			newLastIndex++
+		}
+		if err := re.SetProperty(NameStr("lastIndex"), JSNumber(newLastIndex), vm); err != nil {
+			return nil, err
+		}
+	}
+
+	groupNames := rp.compiled.GroupNames()
+	result := NewJSArray()
+	var groupsObj *JSObject
+	for g := 0; g*2 < len(idx); g++ {
+		var value JSValue = JSUndefined{}
+		if idx[g*2] >= 0 {
+			value = JSString(str[idx[g*2]:idx[g*2+1]])
+		}
+		result.arrayPart = append(result.arrayPart, value)
+
+		if g > 0 && g < len(groupNames) && groupNames[g] != "" {
+			if groupsObj == nil {
+				obj := NewJSObject(nil)
+				groupsObj = &obj
+			}
+			if err := groupsObj.SetProperty(NameStr(groupNames[g]), value, vm); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := result.SetProperty(NameStr("index"), JSNumber(idx[0]), vm); err != nil {
+		return nil, err
+	}
+	if err := result.SetProperty(NameStr("input"), JSString(str), vm); err != nil {
+		return nil, err
+	}
+	var groupsValue JSValue = JSUndefined{}
+	if groupsObj != nil {
+		groupsValue = groupsObj
+	}
+	if err := result.SetProperty(NameStr("groups"), groupsValue, vm); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// regexpArg coerces args[i] into a RegExp object for the String.prototype
+// methods below: passed through unchanged if it already is one, otherwise
+// compiled as a literal pattern with no flags — the same fallback `new
+// RegExp(str)` would give, so "abc".search("b") works without requiring
+// callers to spell out a RegExp themselves.
+func (vm *VM) regexpArg(args []JSValue, i int) (*JSObject, error) {
+	arg := argOrUndefined(args, i)
+	if obj, isObj := arg.(*JSObject); isObj && obj.regexpPart != nil {
+		return obj, nil
+	}
+	pattern, err := vm.coerceToString(arg)
+	if err != nil {
+		return nil, err
+	}
+	return vm.newRegExp(string(pattern), "")
+}
+
+func init() {
+	match := NewNativeFunction([]string{"regexp"}, func(vm *VM, subject JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		str, err := vm.coerceToString(subject)
+		if err != nil {
+			return nil, err
+		}
+		re, err := vm.regexpArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return vm.stringMatch(re, string(str))
+	})
+	ProtoString.SetProperty(NameStr("match"), &match, nil)
+
+	matchAll := NewNativeFunction([]string{"regexp"}, func(vm *VM, subject JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		str, err := vm.coerceToString(subject)
+		if err != nil {
+			return nil, err
+		}
+		re, err := vm.regexpArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if re.regexpPart.flags&RegexpGlobal == 0 {
+			return nil, vm.ThrowError("TypeError", "String.prototype.matchAll called with a non-global RegExp argument")
+		}
+		return vm.stringMatchAll(re, string(str))
+	})
+	ProtoString.SetProperty(NameStr("matchAll"), &matchAll, nil)
+
+	replace := NewNativeFunction([]string{"regexp", "replacement"}, func(vm *VM, subject JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		str, err := vm.coerceToString(subject)
+		if err != nil {
+			return nil, err
+		}
+		re, err := vm.regexpArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		return vm.stringReplace(re, string(str), argOrUndefined(args, 1))
+	})
+	ProtoString.SetProperty(NameStr("replace"), &replace, nil)
+
+	search := NewNativeFunction([]string{"regexp"}, func(vm *VM, subject JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		str, err := vm.coerceToString(subject)
+		if err != nil {
+			return nil, err
+		}
+		re, err := vm.regexpArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		idx := re.regexpPart.compiled.FindSubmatchIndex(string(str), 0)
+		if idx == nil {
+			return JSNumber(-1), nil
+		}
+		return JSNumber(idx[0]), nil
+	})
+	ProtoString.SetProperty(NameStr("search"), &search, nil)
+
+	split := NewNativeFunction([]string{"separator", "limit"}, func(vm *VM, subject JSValue, args []JSValue, _ CallFlags) (JSValue, error) {
+		str, err := vm.coerceToString(subject)
+		if err != nil {
+			return nil, err
+		}
+		re, err := vm.regexpArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		limit := -1
+		if limitArg := argOrUndefined(args, 1); !isUndefined(limitArg) {
+			limitNum, err := vm.coerceToNumber(limitArg)
+			if err != nil {
+				return nil, err
+			}
+			limit = int(limitNum)
+		}
+		return vm.stringSplit(re, string(str), limit)
+	})
+	ProtoString.SetProperty(NameStr("split"), &split, nil)
+}
+
+func isUndefined(v JSValue) bool {
+	_, isUndef := v.(JSUndefined)
+	return isUndef
+}
+
+// stringMatch implements String.prototype.match: re's own exec result for
+// a non-global re, or an array of every whole-match string (null if there
+// were none) when re is global.
+func (vm *VM) stringMatch(re *JSObject, str string) (JSValue, error) {
+	if re.regexpPart.flags&RegexpGlobal == 0 {
+		return vm.execRegexp(re, str)
+	}
+
+	if err := re.SetProperty(NameStr("lastIndex"), JSNumber(0), vm); err != nil {
+		return nil, err
+	}
+
+	matches := NewJSArray()
+	for {
+		m, err := vm.execRegexp(re, str)
+		if err != nil {
+			return nil, err
+		}
+		if isNull(m) {
+			break
+		}
+		whole, err := m.(*JSObject).GetIndex(0)
+		if err != nil {
+			return nil, err
+		}
+		matches.arrayPart = append(matches.arrayPart, whole)
+	}
+	if len(matches.arrayPart) == 0 {
+		return JSNull{}, nil
+	}
+	return matches, nil
+}
+
+// stringMatchAll implements String.prototype.matchAll: every exec result
+// against re (which must be global), collected eagerly into an array —
+// this package has no general iterator protocol yet (see generator.go) for
+// matchAll to lazily vend results through the way the spec does.
+func (vm *VM) stringMatchAll(re *JSObject, str string) (JSValue, error) {
+	if err := re.SetProperty(NameStr("lastIndex"), JSNumber(0), vm); err != nil {
+		return nil, err
+	}
+
+	results := NewJSArray()
+	for {
+		m, err := vm.execRegexp(re, str)
+		if err != nil {
+			return nil, err
+		}
+		if isNull(m) {
+			break
+		}
+		results.arrayPart = append(results.arrayPart, m)
+	}
+	return results, nil
+}
+
+func isNull(v JSValue) bool {
+	_, isNull := v.(JSNull)
+	return isNull
+}
+
+// stringReplace implements String.prototype.replace: replacement is
+// either a function, called per match with (wholeMatch, group1, ...,
+// index, str), or a string pattern expanded per match ($&, $`, $', $1-$9,
+// $$). re global means every match is replaced; otherwise only the first.
+func (vm *VM) stringReplace(re *JSObject, str string, replacement JSValue) (JSValue, error) {
+	global := re.regexpPart.flags&RegexpGlobal != 0
+	if err := re.SetProperty(NameStr("lastIndex"), JSNumber(0), vm); err != nil {
+		return nil, err
+	}
+
+	replacementFn, callReplacement := replacement.(*JSObject)
+	callReplacement = callReplacement && replacementFn.funcPart != nil
+
+	var replacementStr string
+	if !callReplacement {
+		s, err := vm.coerceToString(replacement)
+		if err != nil {
+			return nil, err
+		}
+		replacementStr = string(s)
+	}
+
+	var out strings.Builder
+	last := 0
+	for {
+		m, err := vm.execRegexp(re, str)
+		if err != nil {
+			return nil, err
+		}
+		if isNull(m) {
+			break
+		}
+		result := m.(*JSObject)
+
+		indexVal, err := result.GetProperty(NameStr("index"), vm)
+		if err != nil {
+			return nil, err
+		}
+		index := int(indexVal.(JSNumber))
+
+		whole, err := result.GetIndex(0)
+		if err != nil {
+			return nil, err
+		}
+		wholeStr := string(whole.(JSString))
+
+		out.WriteString(str[last:index])
+
+		if callReplacement {
+			callArgs := append(append([]JSValue{}, result.arrayPart...), JSNumber(index), JSString(str))
+			ret, err := replacementFn.Invoke(vm, JSUndefined{}, callArgs, CallFlags{})
+			if err != nil {
+				return nil, err
+			}
+			retStr, err := vm.coerceToString(ret)
+			if err != nil {
+				return nil, err
+			}
+			out.WriteString(string(retStr))
+		} else {
+			out.WriteString(expandReplacement(replacementStr, wholeStr, str, index, result.arrayPart))
+		}
+
+		last = index + len(wholeStr)
+		if !global {
+			break
+		}
+	}
+	out.WriteString(str[last:])
+
+	return JSString(out.String()), nil
+}
+
+// expandReplacement substitutes $&, $`, $', $1-$9 and $$ into template,
+// the string-replacement half of String.prototype.replace. groups is the
+// exec result ([wholeMatch, group1, ...]); a $n naming a group the pattern
+// doesn't have is copied through unchanged.
+func expandReplacement(template, wholeMatch, str string, index int, groups []JSValue) string {
+	var out strings.Builder
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c != '$' || i+1 >= len(template) {
+			out.WriteByte(c)
+			continue
+		}
+
+		switch next := template[i+1]; {
+		case next == '$':
+			out.WriteByte('$')
+			i++
+		case next == '&':
+			out.WriteString(wholeMatch)
+			i++
+		case next == '`':
+			out.WriteString(str[:index])
+			i++
+		case next == '\'':
+			out.WriteString(str[index+len(wholeMatch):])
+			i++
+		case next >= '1' && next <= '9' && int(next-'0') < len(groups):
+			if s, ok := groups[next-'0'].(JSString); ok {
+				out.WriteString(string(s))
+			}
+			i++
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// stringSplit implements String.prototype.split against a RegExp
+// separator: the pieces between non-overlapping matches, with any capture
+// groups interleaved, stopping once limit pieces have been collected
+// (limit < 0 means no limit).
+func (vm *VM) stringSplit(re *JSObject, str string, limit int) (JSValue, error) {
+	result := NewJSArray()
+	push := func(v JSValue) bool {
+		if limit >= 0 && len(result.arrayPart) >= limit {
+			return false
+		}
+		result.arrayPart = append(result.arrayPart, v)
+		return true
+	}
+
+	if str == "" {
+		if re.regexpPart.compiled.FindSubmatchIndex(str, 0) == nil {
+			push(JSString(""))
+		}
+		return result, nil
+	}
+
+	last, pos := 0, 0
+	for pos <= len(str) {
+		idx := re.regexpPart.compiled.FindSubmatchIndex(str, pos)
+		if idx == nil {
+			break
+		}
+		if idx[0] == idx[1] && idx[0] == last {
+			// zero-width match right where the previous piece ended:
+			// advance past it instead of splitting on nothing forever
+			pos = idx[1] + 1
+			continue
+		}
+
+		if !push(JSString(str[last:idx[0]])) {
+			return result, nil
+		}
+		for g := 1; g*2 < len(idx); g++ {
+			var groupVal JSValue = JSUndefined{}
+			if idx[g*2] >= 0 {
+				groupVal = JSString(str[idx[g*2]:idx[g*2+1]])
+			}
+			if !push(groupVal) {
+				return result, nil
+			}
+		}
+
+		last = idx[1]
+		pos = idx[1]
+		if idx[0] == idx[1] {
+			pos++
+		}
+	}
+
+	push(JSString(str[last:]))
+	return result, nil
+}
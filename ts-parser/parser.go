@@ -4,13 +4,44 @@ import (
 	"context"
 	"fmt"
 	"io"
-
-	// "log"
+	"strings"
 
 	ts "github.com/smacker/go-tree-sitter"
 	javascript "github.com/smacker/go-tree-sitter/javascript"
 )
 
+// Tree is a parsed source file, wrapping the tree-sitter parse tree together
+// with the source bytes it was parsed from (diagnostics need both to print
+// a snippet).
+type Tree struct {
+	Root   *ts.Node
+	Source []byte
+}
+
+// Range identifies a span of source text by 1-based line/column, the same
+// shape the Language Server Protocol uses, so a future LSP frontend can
+// forward it without translation.
+type Range struct {
+	StartLine, StartCol int
+	EndLine, EndCol     int
+}
+
+// Diagnostic describes one syntax problem found anywhere in a parse tree.
+// Unlike the old first-error-wins behavior, Parse collects every one of
+// these in a single pass.
+type Diagnostic struct {
+	Path    string
+	Range   Range
+	Kind    string // "error" or "missing"
+	Message string
+	Snippet string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.Path, d.Range.StartLine, d.Range.StartCol, d.Kind, d.Message)
+}
+
+// ParseReader reads rdr fully and parses it as JavaScript.
 func ParseReader(path string, rdr io.Reader) (err error) {
 	bytes, err := io.ReadAll(rdr)
 	if err == nil {
@@ -19,26 +50,94 @@ func ParseReader(path string, rdr io.Reader) (err error) {
 	return
 }
 
-func ParseBytes(path string, bytes []byte) (err error) {
+// ParseBytes parses src and returns a single aggregate error describing
+// every syntax problem found, or nil if src is well-formed. Callers that
+// need the individual diagnostics (e.g. to report them one at a time, or to
+// keep going past the first one) should use Parse instead.
+func ParseBytes(path string, src []byte) error {
+	_, diags, err := Parse(path, src)
+	if err != nil {
+		return err
+	}
+	if len(diags) == 0 {
+		return nil
+	}
+
+	lines := make([]string, len(diags))
+	for i, d := range diags {
+		lines[i] = d.String()
+	}
+	return fmt.Errorf("%d syntax error(s):\n%s", len(diags), strings.Join(lines, "\n"))
+}
+
+// Parse parses src as JavaScript with tree-sitter and walks the whole
+// resulting tree once, collecting every error/missing node into a
+// Diagnostic rather than bailing out on the first one.
+func Parse(path string, src []byte) (*Tree, []Diagnostic, error) {
 	parser := ts.NewParser()
 	parser.SetLanguage(javascript.GetLanguage())
 
 	ctx := context.TODO()
-	tree, err := parser.ParseCtx(ctx, nil, bytes)
+	tsTree, err := parser.ParseCtx(ctx, nil, src)
 	if err != nil {
-		return
+		return nil, nil, err
 	}
 
-	iter := ts.NewIterator(tree.RootNode(), ts.DFSMode)
+	tree := &Tree{Root: tsTree.RootNode(), Source: src}
+
+	var diags []Diagnostic
+	iter := ts.NewIterator(tree.Root, ts.DFSMode)
 	err = iter.ForEach(func(node *ts.Node) error {
-		if node.IsError() {
-			return fmt.Errorf("syntax error: %s", node.String())
+		if d, isDiag := diagnosticFor(path, tree.Source, node); isDiag {
+			diags = append(diags, d)
 		}
 		return nil
 	})
-
 	if err == io.EOF {
 		err = nil
 	}
-	return
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tree, diags, nil
+}
+
+func diagnosticFor(path string, src []byte, node *ts.Node) (Diagnostic, bool) {
+	var kind, message string
+	switch {
+	case node.IsMissing():
+		kind = "missing"
+		message = fmt.Sprintf("missing %s", node.Type())
+	case node.IsError():
+		kind = "error"
+		message = fmt.Sprintf("unexpected %s", node.Type())
+	default:
+		return Diagnostic{}, false
+	}
+
+	start := node.StartPoint()
+	end := node.EndPoint()
+
+	snippet := ""
+	startByte, endByte := node.StartByte(), node.EndByte()
+	if int(endByte) <= len(src) && startByte <= endByte {
+		snippet = string(src[startByte:endByte])
+	}
+	if strings.Contains(snippet, "\n") {
+		snippet = ""
+	}
+
+	return Diagnostic{
+		Path: path,
+		Range: Range{
+			StartLine: int(start.Row) + 1,
+			StartCol:  int(start.Column) + 1,
+			EndLine:   int(end.Row) + 1,
+			EndCol:    int(end.Column) + 1,
+		},
+		Kind:    kind,
+		Message: message,
+		Snippet: snippet,
+	}, true
 }